@@ -0,0 +1,60 @@
+package authority
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"strings"
+)
+
+// Get the addresses we have for the targets of MX, SRV, and NS records in an answer, which includes
+// glue for nameservers within our zones, so clients do not have to look them up separately
+func AdditionalView(view string, answers []dns.RR, database *bolt.DB) []dns.RR {
+	// Targets already answered for do not need to be repeated
+	seen := make(map[string]bool)
+	for _, rr := range answers {
+		if rr.Header().Rrtype == dns.TypeA || rr.Header().Rrtype == dns.TypeAAAA {
+			seen[strings.ToLower(rr.Header().Name)] = true
+		}
+	}
+
+	var additional []dns.RR
+	for _, rr := range answers {
+		var target string
+		switch record := rr.(type) {
+		case *dns.MX:
+			target = record.Mx
+		case *dns.SRV:
+			target = record.Target
+		case *dns.NS:
+			target = record.Ns
+		default:
+			continue
+		}
+
+		// Services that are explicitly unavailable have the root as their target
+		target = dns.Fqdn(strings.ToLower(target))
+		if target == "." || seen[target] {
+			continue
+		}
+		seen[target] = true
+
+		zone, err := db.FindZone(target, database)
+		if err != nil {
+			log.Printf("Failed to find zone for '%s': %v", target, err)
+			continue
+		}
+
+		// Targets that do not exist can still be answered by a wildcard
+		name := target
+		if wildcard := db.Get.In(view).Wildcard(target); wildcard != "" {
+			name = wildcard
+		}
+
+		additional = append(additional, LookupView(view, target, name, dns.TypeA, dns.ClassINET, zone)...)
+		additional = append(additional, LookupView(view, target, name, dns.TypeAAAA, dns.ClassINET, zone)...)
+	}
+
+	return additional
+}
@@ -0,0 +1,34 @@
+package authority
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	"strings"
+)
+
+// Ways of answering ANY queries for names we host
+const (
+	// A single synthesized HINFO record, as described in RFC 8482
+	AnyHINFO = "hinfo"
+	// Every record the name has, for debugging internal data
+	AnyAll = "all"
+)
+
+// Answer an ANY query for a name in the data of a view. Names without records get no answers, so they are
+// denied like any other query.
+func AnyView(view, owner, name string, qclass uint16, zone *db.Zone, mode string) []dns.RR {
+	apex := zone != nil && dns.Fqdn(zone.Name) == strings.ToLower(owner)
+	if !apex && !db.Get.In(view).Exists(name) {
+		return nil
+	}
+
+	if mode != AnyAll {
+		return []dns.RR{&dns.HINFO{Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeHINFO, Class: qclass, Ttl: TTL(0, zone)}, Cpu: "RFC8482"}}
+	}
+
+	var answers []dns.RR
+	for _, recordType := range append([]string{"SOA"}, db.RecordTypes...) {
+		answers = append(answers, LookupView(view, owner, name, dns.StringToType[recordType], qclass, zone)...)
+	}
+	return answers
+}
@@ -0,0 +1,157 @@
+package authority
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+)
+
+// Look up the records of a type for a name in our own data, answering with the queried owner name
+func Lookup(owner, name string, qtype, qclass uint16, zone *db.Zone) []dns.RR {
+	return LookupView("", owner, name, qtype, qclass, zone)
+}
+
+// Look up the records of a type for a name in the data of a view
+func LookupView(view, owner, name string, qtype, qclass uint16, zone *db.Zone) []dns.RR {
+	return lookupIn(nil, view, owner, name, qtype, qclass, zone)
+}
+
+// Look up records within a transaction, so changes made in it are seen
+func lookupIn(tx *bolt.Tx, view, owner, name string, qtype, qclass uint16, zone *db.Zone) []dns.RR {
+	records := db.Get.In(view).Within(tx)
+	var answers []dns.RR
+	hdr := dns.RR_Header{Name: owner, Rrtype: qtype, Class: qclass}
+
+	// Do different things based on record type
+	switch qtype {
+	case dns.TypeA:
+		for _, record := range records.A(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.A{Hdr: hdr, A: record.Address})
+		}
+	case dns.TypeAAAA:
+		for _, record := range records.AAAA(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.AAAA{Hdr: hdr, AAAA: record.Address})
+		}
+	case dns.TypeCNAME:
+		for _, record := range records.CNAME(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.CNAME{Hdr: hdr, Target: record.Target})
+		}
+	case dns.TypeMX:
+		for _, record := range records.MX(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.MX{Hdr: hdr, Preference: record.Priority, Mx: record.Host})
+		}
+	case dns.TypeLOC:
+		for _, record := range records.LOC(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			locString, vers := record.ToParsable()
+			answers = append(answers, util.ParseLOCString(locString, vers, hdr))
+		}
+	case dns.TypeSRV:
+		for _, record := range records.SRV(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.SRV{Hdr: hdr, Priority: record.Priority, Weight: record.Weight, Port: record.Port, Target: record.Target})
+		}
+	case dns.TypeSPF:
+		for _, record := range records.SPF(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.SPF{Hdr: hdr, Txt: record.Text})
+		}
+	case dns.TypeTXT:
+		for _, record := range records.TXT(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.TXT{Hdr: hdr, Txt: record.Text})
+		}
+	case dns.TypeNS:
+		for _, record := range records.NS(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.NS{Hdr: hdr, Ns: record.Nameserver})
+		}
+	case dns.TypeCAA:
+		for _, record := range records.CAA(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.CAA{Hdr: hdr, Flag: record.Flag, Tag: record.Tag, Value: record.Content})
+		}
+	case dns.TypePTR:
+		for _, record := range records.PTR(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.PTR{Hdr: hdr, Ptr: record.Domain})
+		}
+	case dns.TypeCERT:
+		for _, record := range records.CERT(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.CERT{Hdr: hdr, Type: record.Type, KeyTag: record.KeyTag, Algorithm: record.Algorithm, Certificate: record.Certificate})
+		}
+	case dns.TypeDNSKEY:
+		for _, record := range records.DNSKEY(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.DNSKEY{Hdr: hdr, Flags: record.Flags, Protocol: record.Protocol, Algorithm: record.Algorithm, PublicKey: record.PublicKey})
+		}
+	case dns.TypeDS:
+		for _, record := range records.DS(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.DS{Hdr: hdr, KeyTag: record.KeyTag, Algorithm: record.Algorithm, DigestType: record.DigestType, Digest: record.Digest})
+		}
+	case dns.TypeNAPTR:
+		for _, record := range records.NAPTR(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.NAPTR{Hdr: hdr, Order: record.Order, Preference: record.Preference, Flags: record.Flags, Service: record.Service, Regexp: record.Regexp, Replacement: record.Replacement})
+		}
+	case dns.TypeSMIMEA:
+		for _, record := range records.SMIMEA(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.SMIMEA{Hdr: hdr, Usage: record.Usage, Selector: record.Selector, MatchingType: record.MatchingType, Certificate: record.Certificate})
+		}
+	case dns.TypeSSHFP:
+		for _, record := range records.SSHFP(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.SSHFP{Hdr: hdr, Algorithm: record.Algorithm, Type: record.Type, FingerPrint: record.Fingerprint})
+		}
+	case dns.TypeTLSA:
+		for _, record := range records.TLSA(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.TLSA{Hdr: hdr, Usage: record.Usage, Selector: record.Selector, MatchingType: record.MatchingType, Certificate: record.Certificate})
+		}
+	case dns.TypeURI:
+		for _, record := range records.URI(name) {
+			hdr.Ttl = TTL(record.TTL, zone)
+			answers = append(answers, &dns.URI{Hdr: hdr, Priority: record.Priority, Weight: record.Weight, Target: record.Target})
+		}
+	case dns.TypeSOA:
+		if zone != nil && strings.ToLower(owner) == dns.Fqdn(zone.Name) {
+			answers = append(answers, SOA(zone))
+		}
+	}
+
+	return answers
+}
+
+// Get the TTL to serve a record with, falling back to the zone and then server default if unset
+func TTL(recordTTL uint32, zone *db.Zone) uint32 {
+	if recordTTL != 0 {
+		return recordTTL
+	} else if zone != nil && zone.TTL != 0 {
+		return zone.TTL
+	}
+	return viper.GetUint32("dns.ttl")
+}
+
+// Build the SOA record for a zone
+func SOA(zone *db.Zone) *dns.SOA {
+	return &dns.SOA{
+		Hdr: dns.RR_Header{Name: dns.Fqdn(zone.Name), Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: TTL(0, zone)},
+		Ns: dns.Fqdn(zone.Nameserver),
+		Mbox: dns.Fqdn(strings.Replace(zone.Admin, "@", ".", 1)),
+		Serial: zone.Serial,
+		Refresh: zone.Refresh,
+		Retry: zone.Retry,
+		Expire: zone.Expire,
+		Minttl: zone.NegativeTTL,
+	}
+}
@@ -0,0 +1,95 @@
+package authority
+
+import (
+	"fmt"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Write a record received from another server into our own records, within a transaction if one is given
+func Store(tx *bolt.Tx, rr dns.RR) error {
+	name := strings.TrimSuffix(strings.ToLower(rr.Header().Name), ".")
+	ttl := rr.Header().Ttl
+	set := db.Set.Within(tx)
+
+	var err error
+	switch record := rr.(type) {
+	case *dns.A:
+		_, err = set.A(name, "", ttl, record.A.String())
+	case *dns.AAAA:
+		_, err = set.AAAA(name, "", ttl, record.AAAA.String())
+	case *dns.CNAME:
+		_, err = set.CNAME(name, "", ttl, record.Target)
+	case *dns.MX:
+		_, err = set.MX(name, "", ttl, record.Preference, record.Mx)
+	case *dns.LOC:
+		err = storeLOC(tx, name, ttl, record)
+	case *dns.SRV:
+		_, err = set.SRV(name, "", ttl, record.Priority, record.Weight, record.Port, record.Target)
+	case *dns.SPF:
+		_, err = set.SPF(name, "", ttl, record.Txt)
+	case *dns.TXT:
+		_, err = set.TXT(name, "", ttl, record.Txt)
+	case *dns.NS:
+		_, err = set.NS(name, "", ttl, record.Ns)
+	case *dns.CAA:
+		_, err = set.CAA(name, "", ttl, record.Tag, record.Value)
+	case *dns.PTR:
+		_, err = set.PTR(name, "", ttl, record.Ptr)
+	case *dns.CERT:
+		_, err = set.CERT(name, "", ttl, record.Type, record.KeyTag, record.Algorithm, record.Certificate)
+	case *dns.DNSKEY:
+		_, err = set.DNSKEY(name, "", ttl, record.Flags, record.Protocol, record.Algorithm, record.PublicKey)
+	case *dns.DS:
+		_, err = set.DS(name, "", ttl, record.KeyTag, record.Algorithm, record.DigestType, record.Digest)
+	case *dns.NAPTR:
+		_, err = set.NAPTR(name, "", ttl, record.Order, record.Preference, record.Flags, record.Service, record.Regexp, record.Replacement)
+	case *dns.SMIMEA:
+		_, err = set.SMIMEA(name, "", ttl, record.Usage, record.Selector, record.MatchingType, record.Certificate)
+	case *dns.SSHFP:
+		_, err = set.SSHFP(name, "", ttl, record.Algorithm, record.Type, record.FingerPrint)
+	case *dns.TLSA:
+		_, err = set.TLSA(name, "", ttl, record.Usage, record.Selector, record.MatchingType, record.Certificate)
+	case *dns.URI:
+		_, err = set.URI(name, "", ttl, record.Priority, record.Weight, record.Target)
+	default:
+		return fmt.Errorf("unsupported record type %s", dns.TypeToString[rr.Header().Rrtype])
+	}
+
+	// Records we already have are left as they are
+	if err == db.ErrRecordExists {
+		return nil
+	}
+	return err
+}
+
+// Store a LOC record, which is kept in whole degrees, minutes, seconds, and meters
+func storeLOC(tx *bolt.Tx, name string, ttl uint32, record *dns.LOC) error {
+	// Presentation format is: d m s.sss N d m s.sss E alt.m size.m horiz.m vert.m
+	parts := strings.Fields(strings.TrimPrefix(record.String(), record.Hdr.String()))
+	if len(parts) != 12 {
+		return fmt.Errorf("invalid LOC record '%s'", record.String())
+	}
+
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		if i == 3 || i == 7 {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSuffix(part, "m"), 64)
+		if err != nil {
+			return fmt.Errorf("invalid LOC record '%s': %v", record.String(), err)
+		}
+		values[i] = value
+	}
+
+	// Values are rounded and clamped to what fits in storage
+	clamp := func(value, max float64) float64 { return math.Max(0, math.Min(max, math.Round(value))) }
+	_, err := db.Set.Within(tx).LOC(name, "", ttl, record.Version, uint8(clamp(values[9], math.MaxUint8)), uint8(clamp(values[10], math.MaxUint8)), uint8(clamp(values[11], math.MaxUint8)), uint32(clamp(values[8], math.MaxUint32)),
+		uint8(values[0]), uint8(values[1]), uint8(clamp(values[2], 59)), parts[3], uint8(values[4]), uint8(values[5]), uint8(clamp(values[6], 59)), parts[7])
+	return err
+}
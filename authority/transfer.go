@@ -0,0 +1,258 @@
+package authority
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// Number of records sent in each message of a transfer
+const transferChunkSize = 100
+
+// Get every record in a zone except for the SOA
+func Records(zone *db.Zone, database *bolt.DB) ([]dns.RR, error) {
+	names, err := db.ZoneNames(zone.Name, database)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []dns.RR
+	for _, name := range names {
+		for _, recordType := range db.RecordTypes {
+			records = append(records, Lookup(dns.Fqdn(name), dns.Fqdn(name), dns.StringToType[recordType], dns.ClassINET, zone)...)
+		}
+	}
+
+	return records, nil
+}
+
+// Journal the current records of a zone so changes can be sent incrementally
+func Journal(name string, database *bolt.DB) error {
+	zone, err := db.GetZone(name, database)
+	if err != nil || zone == nil {
+		return err
+	}
+
+	records, err := Records(zone, database)
+	if err != nil {
+		return err
+	}
+
+	var presentation []string
+	for _, record := range records {
+		presentation = append(presentation, record.String())
+	}
+
+	return db.JournalZone(zone.Name, presentation, database)
+}
+
+// Journal the zone containing a record name, if any
+func JournalRecord(name string, database *bolt.DB) {
+	zone, err := db.FindZone(dns.Fqdn(name), database)
+	if err != nil {
+		log.Printf("Failed to find zone for '%s': %v", name, err)
+		return
+	} else if zone == nil {
+		return
+	}
+
+	if err := Journal(zone.Name, database); err != nil {
+		log.Printf("Failed to journal zone '%s': %v", zone.Name, err)
+	}
+}
+
+// Answer an AXFR or IXFR query for one of our zones
+func Transfer(w dns.ResponseWriter, m *dns.Msg, database *bolt.DB) {
+	q := m.Question[0]
+
+	zone, err := db.GetZone(q.Name, database)
+	if err != nil {
+		log.Printf("Failed to retrieve zone '%s': %v", q.Name, err)
+		writeError(w, m, dns.RcodeServerFailure)
+		return
+	} else if zone == nil || strings.ToLower(q.Name) != dns.Fqdn(zone.Name) {
+		writeError(w, m, dns.RcodeNotAuth)
+		return
+	} else if !transferAllowed(zone, w, m) {
+		log.Printf("Refused %s of '%s' to %s", dns.TypeToString[q.Qtype], zone.Name, w.RemoteAddr())
+		writeError(w, m, dns.RcodeRefused)
+		return
+	}
+
+	// Make sure the serial and journal reflect the latest records
+	if err := Journal(zone.Name, database); err != nil {
+		log.Printf("Failed to journal zone '%s': %v", zone.Name, err)
+	}
+	if zone, err = db.GetZone(zone.Name, database); err != nil || zone == nil {
+		writeError(w, m, dns.RcodeServerFailure)
+		return
+	}
+
+	// Transfers are only sent over TCP, incremental ones get the SOA over UDP so the client retries with TCP
+	if w.RemoteAddr().Network() != "tcp" {
+		if q.Qtype == dns.TypeAXFR {
+			writeError(w, m, dns.RcodeRefused)
+			return
+		}
+
+		r := new(dns.Msg)
+		r.SetReply(m)
+		r.Authoritative = true
+		r.Answer = []dns.RR{SOA(zone)}
+		signReply(w, m, r)
+		if err := w.WriteMsg(r); err != nil {
+			log.Printf("Unable to send response: %v", err)
+		}
+		return
+	}
+
+	// Fall back to a full transfer when the journal cannot be used
+	var records []dns.RR
+	if q.Qtype == dns.TypeIXFR {
+		records, err = incremental(zone, m, database)
+	}
+	if err == nil && records == nil {
+		records, err = full(zone, database)
+	}
+	if err != nil {
+		log.Printf("Failed to build transfer of '%s': %v", zone.Name, err)
+		writeError(w, m, dns.RcodeServerFailure)
+		return
+	}
+
+	// Send in chunks so large zones span multiple messages
+	ch := make(chan *dns.Envelope)
+	tr := new(dns.Transfer)
+	done := make(chan error)
+	go func() { done <- tr.Out(w, m, ch) }()
+	for len(records) > 0 {
+		size := transferChunkSize
+		if len(records) < size {
+			size = len(records)
+		}
+		ch <- &dns.Envelope{RR: records[:size]}
+		records = records[size:]
+	}
+	close(ch)
+
+	if err := <-done; err != nil {
+		log.Printf("Failed to transfer '%s' to %s: %v", zone.Name, w.RemoteAddr(), err)
+	}
+	if err := w.Close(); err != nil {
+		log.Printf("Failed to close transfer connection: %v", err)
+	}
+}
+
+// Build a full transfer, all records surrounded by the SOA
+func full(zone *db.Zone, database *bolt.DB) ([]dns.RR, error) {
+	records, err := Records(zone, database)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := []dns.RR{SOA(zone)}
+	transfer = append(transfer, records...)
+	return append(transfer, SOA(zone)), nil
+}
+
+// Build an incremental transfer from the journal, nil if the journal cannot be used
+func incremental(zone *db.Zone, m *dns.Msg, database *bolt.DB) ([]dns.RR, error) {
+	var current *dns.SOA
+	for _, rr := range m.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			current = soa
+		}
+	}
+	if current == nil {
+		return nil, nil
+	}
+
+	// Secondaries that are up to date only get the SOA
+	if current.Serial == zone.Serial {
+		return []dns.RR{SOA(zone)}, nil
+	}
+
+	entries, err := db.GetJournal(zone.Name, current.Serial, database)
+	if err != nil || len(entries) == 0 || entries[len(entries)-1].To != zone.Serial {
+		return nil, err
+	}
+
+	// Each change is the old SOA and deleted records, then the new SOA and added records
+	transfer := []dns.RR{SOA(zone)}
+	for _, entry := range entries {
+		for _, change := range []struct {
+			serial  uint32
+			records []string
+		}{{entry.From, entry.Deleted}, {entry.To, entry.Added}} {
+			soa := SOA(zone)
+			soa.Serial = change.serial
+			transfer = append(transfer, soa)
+
+			for _, record := range change.records {
+				rr, err := dns.NewRR(record)
+				if err != nil {
+					return nil, err
+				}
+				transfer = append(transfer, rr)
+			}
+		}
+	}
+
+	return append(transfer, SOA(zone)), nil
+}
+
+// Check if a client may transfer a zone, either by address or by TSIG key
+func transferAllowed(zone *db.Zone, w dns.ResponseWriter, m *dns.Msg) bool {
+	if tsig := m.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		for _, key := range zone.TransferKeys {
+			if dns.Fqdn(strings.ToLower(key)) == strings.ToLower(tsig.Hdr.Name) {
+				return true
+			}
+		}
+	}
+
+	return addressAllowed(w.RemoteAddr(), zone.AllowTransfer)
+}
+
+// Check if an address matches any of a list of addresses and prefixes
+func addressAllowed(addr net.Addr, allowed []string) bool {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	default:
+		return false
+	}
+
+	for _, entry := range allowed {
+		if _, prefix, err := net.ParseCIDR(entry); err == nil && prefix.Contains(ip) {
+			return true
+		} else if net.ParseIP(entry).Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign a reply with the key of the query, if it was signed
+func signReply(w dns.ResponseWriter, m, r *dns.Msg) {
+	if tsig := m.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		r.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+	}
+}
+
+// Reply with only a response code
+func writeError(w dns.ResponseWriter, m *dns.Msg, rcode int) {
+	r := new(dns.Msg)
+	r.SetRcode(m, rcode)
+	signReply(w, m, r)
+	if err := w.WriteMsg(r); err != nil {
+		log.Printf("Unable to send response: %v", err)
+	}
+}
@@ -0,0 +1,354 @@
+package authority
+
+import (
+	"fmt"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"strings"
+)
+
+// Accept dynamic updates on top of the messages accepted by default, for use as dns.Server.MsgAcceptFunc
+func AcceptMessage(dh dns.Header) dns.MsgAcceptAction {
+	isResponse := dh.Bits&(1<<15) != 0
+	if opcode := int(dh.Bits>>11) & 0xF; opcode == dns.OpcodeUpdate && !isResponse {
+		if dh.Qdcount != 1 {
+			return dns.MsgReject
+		}
+		return dns.MsgAccept
+	}
+
+	return dns.DefaultMsgAcceptFunc(dh)
+}
+
+// Apply an RFC 2136 dynamic update to one of our zones, authorized by the role its TSIG key maps to
+func Update(w dns.ResponseWriter, m *dns.Msg, keyRoles map[string]string, database *bolt.DB) *dns.Msg {
+	r := new(dns.Msg)
+	r.SetReply(m)
+	signReply(w, m, r)
+
+	// The zone section holds exactly one SOA question for the zone apex
+	if len(m.Question) != 1 || m.Question[0].Qtype != dns.TypeSOA {
+		r.Rcode = dns.RcodeFormatError
+		return r
+	}
+	zone, err := db.GetZone(m.Question[0].Name, database)
+	if err != nil {
+		log.Printf("Failed to retrieve zone '%s': %v", m.Question[0].Name, err)
+		r.Rcode = dns.RcodeServerFailure
+		return r
+	} else if zone == nil || strings.ToLower(m.Question[0].Name) != dns.Fqdn(zone.Name) {
+		r.Rcode = dns.RcodeNotAuth
+		return r
+	} else if zone.Secondary() {
+		r.Rcode = dns.RcodeRefused
+		return r
+	}
+
+	// Updates must be signed with a key that maps to a role
+	role, rcode := updateRole(w, m, keyRoles, database)
+	if rcode != dns.RcodeSuccess {
+		r.Rcode = rcode
+		return r
+	}
+
+	// Checks and changes happen in one transaction, so concurrent updates are applied one after the other
+	changed := false
+	if err := database.Update(func(tx *bolt.Tx) error {
+		if r.Rcode = checkPrerequisites(tx, m.Answer, zone); r.Rcode != dns.RcodeSuccess {
+			return nil
+		}
+		if r.Rcode = checkUpdates(m.Ns, zone, role); r.Rcode != dns.RcodeSuccess {
+			return nil
+		}
+
+		// The update section is applied all or nothing
+		for _, rr := range m.Ns {
+			applied, err := applyUpdate(tx, rr, zone)
+			if err != nil {
+				return fmt.Errorf("failed to apply update '%s': %v", rr.String(), err)
+			}
+			changed = changed || applied
+		}
+		return nil
+	}); err != nil {
+		log.Printf("Failed to update zone '%s': %v", zone.Name, err)
+		r.Rcode = dns.RcodeServerFailure
+		return r
+	}
+
+	// Update the serial and journal of the zone, unless nothing changed
+	if changed {
+		if err := Journal(zone.Name, database); err != nil {
+			log.Printf("Failed to journal zone '%s': %v", zone.Name, err)
+		}
+	}
+
+	return r
+}
+
+// Find the role of the key an update was signed with
+func updateRole(w dns.ResponseWriter, m *dns.Msg, keyRoles map[string]string, database *bolt.DB) (*db.Role, int) {
+	tsig := m.IsTsig()
+	if tsig == nil || w.TsigStatus() != nil {
+		return nil, dns.RcodeRefused
+	}
+
+	name, ok := keyRoles[strings.ToLower(tsig.Hdr.Name)]
+	if !ok || name == "" {
+		return nil, dns.RcodeRefused
+	}
+
+	// Roles that do not exist would allow everything
+	role, err := db.GetRole(name, database)
+	if err != nil {
+		log.Printf("Failed to retrieve role '%s': %v", name, err)
+		return nil, dns.RcodeServerFailure
+	} else if role.Name == "" && name != "admin" {
+		log.Printf("TSIG key '%s' maps to role '%s' which does not exist", tsig.Hdr.Name, name)
+		return nil, dns.RcodeRefused
+	}
+
+	return role, dns.RcodeSuccess
+}
+
+// Check the prerequisite section of an update as described in RFC 2136 section 3.2
+func checkPrerequisites(tx *bolt.Tx, prerequisites []dns.RR, zone *db.Zone) int {
+	// Value dependent prerequisites are compared as whole RRsets
+	expected := make(map[string][]dns.RR)
+
+	for _, rr := range prerequisites {
+		hdr := rr.Header()
+		if hdr.Ttl != 0 {
+			return dns.RcodeFormatError
+		} else if !inZone(hdr.Name, zone) {
+			return dns.RcodeNotZone
+		}
+
+		name := dns.Fqdn(strings.ToLower(hdr.Name))
+		switch hdr.Class {
+		case dns.ClassANY:
+			if hdr.Rrtype == dns.TypeANY && !nameInUse(tx, name, zone) {
+				return dns.RcodeNameError
+			} else if hdr.Rrtype != dns.TypeANY && len(lookupIn(tx, "", name, name, hdr.Rrtype, dns.ClassINET, zone)) == 0 {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if hdr.Rrtype == dns.TypeANY && nameInUse(tx, name, zone) {
+				return dns.RcodeYXDomain
+			} else if hdr.Rrtype != dns.TypeANY && len(lookupIn(tx, "", name, name, hdr.Rrtype, dns.ClassINET, zone)) != 0 {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			key := name + "/" + dns.TypeToString[hdr.Rrtype]
+			expected[key] = append(expected[key], rr)
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for _, rrset := range expected {
+		name := dns.Fqdn(strings.ToLower(rrset[0].Header().Name))
+		if !sameRdata(lookupIn(tx, "", name, name, rrset[0].Header().Rrtype, dns.ClassINET, zone), rrset) {
+			return dns.RcodeNXRrset
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// Check the update section before changing anything, as described in RFC 2136 section 3.4.1
+func checkUpdates(updates []dns.RR, zone *db.Zone, role *db.Role) int {
+	for _, rr := range updates {
+		hdr := rr.Header()
+		if !inZone(hdr.Name, zone) {
+			return dns.RcodeNotZone
+		}
+
+		switch hdr.Class {
+		case dns.ClassINET:
+			if metaType(hdr.Rrtype) || hdr.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassANY:
+			if hdr.Ttl != 0 || (hdr.Rrtype != dns.TypeANY && metaType(hdr.Rrtype)) {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassNONE:
+			if hdr.Ttl != 0 || metaType(hdr.Rrtype) || hdr.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+
+		// Only types we can store are supported, the SOA is managed through the zone
+		if hdr.Rrtype != dns.TypeANY && hdr.Rrtype != dns.TypeSOA && !supportedType(hdr.Rrtype) {
+			return dns.RcodeNotImplemented
+		}
+
+		// Names are checked against the role without the trailing dot, like the records API
+		if allowed, err := role.Evaluate(strings.TrimSuffix(strings.ToLower(hdr.Name), ".")); err != nil {
+			log.Printf("Failed to evaluate role '%s': %v", role.Name, err)
+			return dns.RcodeServerFailure
+		} else if !allowed {
+			return dns.RcodeRefused
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// Apply a single change from the update section, reporting if it changed anything
+func applyUpdate(tx *bolt.Tx, rr dns.RR, zone *db.Zone) (bool, error) {
+	hdr := rr.Header()
+	name := dns.Fqdn(strings.ToLower(hdr.Name))
+	stored := strings.TrimSuffix(name, ".")
+	apex := name == dns.Fqdn(zone.Name)
+	deletes := db.Delete.Within(tx)
+
+	// The SOA is managed through the zones API
+	if hdr.Rrtype == dns.TypeSOA {
+		return false, nil
+	}
+
+	switch hdr.Class {
+	case dns.ClassINET:
+		// CNAMEs cannot coexist with other data
+		if hdr.Rrtype == dns.TypeCNAME && otherDataExists(tx, name, zone) {
+			return false, nil
+		} else if hdr.Rrtype != dns.TypeCNAME && len(lookupIn(tx, "", name, name, dns.TypeCNAME, dns.ClassINET, zone)) != 0 {
+			return false, nil
+		}
+
+		// Adding a record that already exists only changes its TTL
+		existing := lookupIn(tx, "", name, name, hdr.Rrtype, dns.ClassINET, zone)
+		var keep []dns.RR
+		for _, current := range existing {
+			if !dns.IsDuplicate(current, rr) {
+				keep = append(keep, current)
+			} else if current.Header().Ttl == hdr.Ttl {
+				return false, nil
+			}
+		}
+
+		// A name can only have a single CNAME
+		if hdr.Rrtype == dns.TypeCNAME {
+			return true, replaceSet(tx, stored, hdr.Rrtype, []dns.RR{rr})
+		} else if len(keep) == len(existing) {
+			return true, Store(tx, rr)
+		}
+		return true, replaceSet(tx, stored, hdr.Rrtype, append(keep, rr))
+
+	case dns.ClassANY:
+		// The NS records of the apex can never be deleted as a whole
+		types := db.RecordTypes
+		if hdr.Rrtype != dns.TypeANY {
+			types = []string{dns.TypeToString[hdr.Rrtype]}
+		}
+
+		changed := false
+		for _, recordType := range types {
+			if apex && recordType == "NS" {
+				continue
+			} else if len(lookupIn(tx, "", name, name, dns.StringToType[recordType], dns.ClassINET, zone)) == 0 {
+				continue
+			} else if err := deletes.Set(recordType, stored); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+		return changed, nil
+
+	case dns.ClassNONE:
+		// Rebuild the set without the removed record
+		existing := lookupIn(tx, "", name, name, hdr.Rrtype, dns.ClassINET, zone)
+		var keep []dns.RR
+		for _, current := range existing {
+			if !sameRdata([]dns.RR{current}, []dns.RR{rr}) {
+				keep = append(keep, current)
+			}
+		}
+
+		// The last NS record of the apex cannot be removed
+		if apex && hdr.Rrtype == dns.TypeNS && len(keep) == 0 {
+			return false, nil
+		} else if len(keep) != len(existing) {
+			return true, replaceSet(tx, stored, hdr.Rrtype, keep)
+		}
+	}
+
+	return false, nil
+}
+
+// Replace an entire record set with new records
+func replaceSet(tx *bolt.Tx, name string, rrtype uint16, records []dns.RR) error {
+	if err := db.Delete.Within(tx).Set(dns.TypeToString[rrtype], name); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := Store(tx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check if a name is the zone apex or below it
+func inZone(name string, zone *db.Zone) bool {
+	name = strings.ToLower(dns.Fqdn(name))
+	return name == dns.Fqdn(zone.Name) || strings.HasSuffix(name, "."+dns.Fqdn(zone.Name))
+}
+
+// Check if a name owns any records, the SOA included
+func nameInUse(tx *bolt.Tx, name string, zone *db.Zone) bool {
+	return name == dns.Fqdn(zone.Name) || db.Get.Within(tx).Exists(name)
+}
+
+// Check if a name has records other than a CNAME
+func otherDataExists(tx *bolt.Tx, name string, zone *db.Zone) bool {
+	for _, recordType := range db.RecordTypes {
+		if recordType != "CNAME" && len(lookupIn(tx, "", name, name, dns.StringToType[recordType], dns.ClassINET, zone)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if a type is only used in queries or for transport, such as ANY, AXFR, or TSIG
+func metaType(rrtype uint16) bool {
+	return rrtype == dns.TypeOPT || (rrtype >= 128 && rrtype <= 255)
+}
+
+// Check if a type can be stored in our records
+func supportedType(rrtype uint16) bool {
+	for _, recordType := range db.RecordTypes {
+		if dns.StringToType[recordType] == rrtype {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare two record sets by their data alone, ignoring TTL and class
+func sameRdata(a, b []dns.RR) bool {
+	rdata := func(set []dns.RR) map[string]bool {
+		values := make(map[string]bool)
+		for _, rr := range set {
+			values[strings.TrimPrefix(rr.String(), rr.Header().String())] = true
+		}
+		return values
+	}
+
+	left, right := rdata(a), rdata(b)
+	if len(left) != len(right) {
+		return false
+	}
+	for value := range left {
+		if !right[value] {
+			return false
+		}
+	}
+	return true
+}
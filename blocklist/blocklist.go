@@ -0,0 +1,304 @@
+package blocklist
+
+import (
+	"fmt"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How blocked names are answered
+const (
+	NXDomain = "nxdomain"
+	NoData   = "nodata"
+	Sinkhole = "sinkhole"
+)
+
+// TTL of answers to blocked names
+const blockedTTL = 60
+
+// The state of a single list
+type Stats struct {
+	Entries int    `json:"entries"`
+	Blocked uint64 `json:"blocked"`
+	Loaded  int64  `json:"loaded"`
+	Error   string `json:"error"`
+}
+
+// A loaded list with its entries indexed by name
+type loadedList struct {
+	blocked   uint64
+	config    db.Blocklist
+	names     map[string]entry
+	wildcards map[string]entry
+	entries   int
+	loaded    int64
+	err       string
+}
+
+// Blocks names from lists of domains, unless they are excepted by the allow-list or an RPZ passthru policy
+type Blocker struct {
+	mu         sync.RWMutex
+	lists      []*loadedList
+	exceptions map[string]bool
+	passthru   *loadedList
+	action     string
+	ipv4       net.IP
+	ipv6       net.IP
+	refresh    time.Duration
+	db         *bolt.DB
+}
+
+// Create a blocker answering with the given action and sinkhole addresses by default.
+// Lists are reloaded from their files every refresh interval.
+func New(action string, ipv4, ipv6 net.IP, refresh time.Duration, database *bolt.DB) (*Blocker, error) {
+	if !ValidAction(action) {
+		return nil, fmt.Errorf("blocklist action must be one of 'nxdomain', 'nodata', or 'sinkhole'")
+	} else if ipv4 == nil || ipv4.To4() == nil {
+		return nil, fmt.Errorf("blocklist sinkhole ipv4 address is invalid")
+	} else if ipv6 == nil {
+		return nil, fmt.Errorf("blocklist sinkhole ipv6 address is invalid")
+	}
+
+	return &Blocker{
+		exceptions: make(map[string]bool),
+		passthru:   newList(db.Blocklist{}),
+		action:     action,
+		ipv4:       ipv4,
+		ipv6:       ipv6,
+		refresh:    refresh,
+		db:         database,
+	}, nil
+}
+
+// Check if an action is one blocked names can be answered with
+func ValidAction(action string) bool {
+	return action == NXDomain || action == NoData || action == Sinkhole
+}
+
+// Check if a format is one lists can be written in
+func ValidFormat(format string) bool {
+	return format == Hosts || format == Domains || format == RPZ
+}
+
+// Reload every list from its file along with the exceptions
+func (b *Blocker) Load() error {
+	configs, err := db.ListBlocklists(b.db)
+	if err != nil {
+		return err
+	}
+	exceptions, err := db.ListBlocklistExceptions(b.db)
+	if err != nil {
+		return err
+	}
+
+	// Keep counts across reloads
+	b.mu.RLock()
+	counts := make(map[string]uint64)
+	for _, l := range b.lists {
+		counts[l.config.Name] = atomic.LoadUint64(&l.blocked)
+	}
+	b.mu.RUnlock()
+
+	var lists []*loadedList
+	passthru := newList(db.Blocklist{})
+	for _, config := range configs {
+		l := newList(config)
+		l.blocked = counts[config.Name]
+
+		entries, err := load(config)
+		if err != nil {
+			log.Printf("Failed to load blocklist '%s': %v", config.Name, err)
+			l.err = err.Error()
+		}
+
+		for _, e := range entries {
+			if e.allow {
+				passthru.add(e)
+			} else {
+				l.add(e)
+				l.entries++
+			}
+
+			// Domain lists block the whole domain
+			if config.Format == Domains {
+				l.wildcards[e.name] = e
+			}
+		}
+		lists = append(lists, l)
+	}
+
+	allowed := make(map[string]bool)
+	for _, exception := range exceptions {
+		allowed[exception] = true
+	}
+
+	b.mu.Lock()
+	b.lists = lists
+	b.exceptions = allowed
+	b.passthru = passthru
+	b.mu.Unlock()
+
+	return nil
+}
+
+// Create an empty list
+func newList(config db.Blocklist) *loadedList {
+	return &loadedList{
+		config:    config,
+		names:     make(map[string]entry),
+		wildcards: make(map[string]entry),
+		loaded:    time.Now().Unix(),
+	}
+}
+
+// Add an entry to a list
+func (l *loadedList) add(e entry) {
+	entries := l.names
+	if e.wildcard {
+		entries = l.wildcards
+	}
+
+	// Names with multiple address records answer with all of them
+	if existing, ok := entries[e.name]; ok && len(existing.addresses) != 0 && len(e.addresses) != 0 {
+		e.addresses = append(existing.addresses, e.addresses...)
+	}
+	entries[e.name] = e
+}
+
+// Read and parse the file of a list
+func load(config db.Blocklist) ([]entry, error) {
+	f, err := os.Open(config.File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parse(f, config.Format, config.File)
+}
+
+// Periodically reload the lists in the background
+func (b *Blocker) Start() {
+	if b.refresh <= 0 {
+		return
+	}
+
+	go func() {
+		for {
+			time.Sleep(b.refresh)
+			if err := b.Load(); err != nil {
+				log.Printf("Failed to reload blocklists: %v", err)
+			}
+		}
+	}()
+}
+
+// Get the state of each list
+func (b *Blocker) Stats() map[string]Stats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := make(map[string]Stats)
+	for _, l := range b.lists {
+		stats[l.config.Name] = Stats{
+			Entries: l.entries,
+			Blocked: atomic.LoadUint64(&l.blocked),
+			Loaded:  l.loaded,
+			Error:   l.err,
+		}
+	}
+	return stats
+}
+
+// Answer a question if its name is blocked, returning whether it was
+func (b *Blocker) Answer(r *dns.Msg, q dns.Question) bool {
+	name := normalize(q.Name)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Exceptions cover the domain and everything below it
+	for parent := name; parent != ""; parent = parentOf(parent) {
+		if b.exceptions[parent] {
+			return false
+		}
+	}
+	if _, ok := b.passthru.match(name); ok {
+		return false
+	}
+
+	for _, l := range b.lists {
+		e, ok := l.match(name)
+		if !ok {
+			continue
+		}
+		atomic.AddUint64(&l.blocked, 1)
+
+		action := e.action
+		if action == "" {
+			action = l.config.Action
+		}
+		if action == "" {
+			action = b.action
+		}
+		b.respond(r, q, action, e.addresses)
+		return true
+	}
+
+	return false
+}
+
+// Write the answer for a blocked name into a response
+func (b *Blocker) respond(r *dns.Msg, q dns.Question, action string, addresses []net.IP) {
+	switch action {
+	case NXDomain:
+		r.Rcode = dns.RcodeNameError
+		return
+	case NoData:
+		return
+	}
+
+	if len(addresses) == 0 {
+		addresses = []net.IP{b.ipv4, b.ipv6}
+	}
+
+	// Other types of records are answered with no data
+	header := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: q.Qclass, Ttl: blockedTTL}
+	for _, address := range addresses {
+		if ip := address.To4(); ip != nil && q.Qtype == dns.TypeA {
+			r.Answer = append(r.Answer, &dns.A{Hdr: header, A: ip})
+		} else if ip == nil && q.Qtype == dns.TypeAAAA {
+			r.Answer = append(r.Answer, &dns.AAAA{Hdr: header, AAAA: address})
+		}
+	}
+}
+
+// Find the entry matching a name, either exactly or through a wildcard on one of its parents
+func (l *loadedList) match(name string) (entry, bool) {
+	if e, ok := l.names[name]; ok {
+		return e, true
+	}
+
+	for parent := parentOf(name); parent != ""; parent = parentOf(parent) {
+		if e, ok := l.wildcards[parent]; ok {
+			return e, true
+		}
+	}
+
+	return entry{}, false
+}
+
+// Remove the first label of a name
+func parentOf(name string) string {
+	if i := strings.Index(name, "."); i != -1 {
+		return name[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package blocklist
+
+import (
+	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Handle the creation of blocklists
+func create(w http.ResponseWriter, r *http.Request, blocker *Blocker, database *bolt.DB) {
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, valid := util.ValidateBody(body, []string{"name", "file", "format", "action"}, map[string]map[string]string{
+		"name": {"type": "string", "required": "true"},
+		"file": {"type": "string", "required": "true"},
+		"format": {"type": "string", "required": "true"},
+		"action": {"type": "string", "required": "false"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	l := db.Blocklist{
+		Name:   body["name"].(string),
+		File:   body["file"].(string),
+		Format: body["format"].(string),
+	}
+	if valid["action"] {
+		l.Action = body["action"].(string)
+	}
+
+	// Names are used in paths, where exceptions are managed
+	if l.Name == "exceptions" || strings.Contains(l.Name, "/") {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'name' must not be 'exceptions' or contain '/'")
+		return
+	} else if !ValidFormat(l.Format) {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'format' must be one of 'hosts', 'domains', or 'rpz'")
+		return
+	} else if l.Action != "" && !ValidAction(l.Action) {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'action' must be one of 'nxdomain', 'nodata', or 'sinkhole'")
+		return
+	}
+
+	// Check list does not exist
+	if existing, err := db.GetBlocklist(l.Name, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve blocklist: "+err.Error())
+		return
+	} else if existing != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "specified blocklist already exists")
+		return
+	}
+
+	// Save to database
+	if err := l.Encode(database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write blocklist to database: "+err.Error())
+		return
+	}
+
+	// Start blocking the new list
+	if err := blocker.Load(); err != nil {
+		log.Printf("Failed to reload blocklists: %v", err)
+	}
+
+	util.Responses.Success(w)
+}
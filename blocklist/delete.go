@@ -0,0 +1,56 @@
+package blocklist
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net/http"
+)
+
+func deleteBlocklist(w http.ResponseWriter, r *http.Request, path string, blocker *Blocker, database *bolt.DB) {
+	// Validate initial request with type, path, and header
+	if r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "blocklist must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check user role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Delete list from database
+	if err := db.DeleteBlocklist(r.URL.Path[len(path):], database); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to delete blocklist: "+err.Error())
+		return
+	}
+
+	// Stop blocking the list
+	if err := blocker.Load(); err != nil {
+		log.Printf("Failed to reload blocklists: %v", err)
+	}
+
+	util.Responses.Success(w)
+}
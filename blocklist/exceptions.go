@@ -0,0 +1,153 @@
+package blocklist
+
+import (
+	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net/http"
+)
+
+// Handle the listing of all exceptions
+func listExceptions(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	if _, err := db.TokenFromString(r.Header.Get("Authorization"), database); err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	exceptions, err := db.ListBlocklistExceptions(database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve exceptions: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, exceptions)
+}
+
+// Handle adding domains that are never blocked
+func addException(w http.ResponseWriter, r *http.Request, blocker *Blocker, database *bolt.DB) {
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, _ := util.ValidateBody(body, []string{"domain"}, map[string]map[string]string{
+		"domain": {"type": "string", "required": "true"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	} else if _, ok := dns.IsDomainName(body["domain"].(string)); !ok {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'domain' must be a valid domain name")
+		return
+	}
+
+	// Save to database
+	if err := db.AddBlocklistException(body["domain"].(string), database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write exception to database: "+err.Error())
+		return
+	}
+
+	// Stop blocking the domain
+	if err := blocker.Load(); err != nil {
+		log.Printf("Failed to reload blocklists: %v", err)
+	}
+
+	util.Responses.Success(w)
+}
+
+// Handle removing exceptions
+func deleteException(w http.ResponseWriter, r *http.Request, path string, blocker *Blocker, database *bolt.DB) {
+	// Validate initial request with type, path, and header
+	if r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path) <= len(path) {
+		util.Responses.Error(w, http.StatusBadRequest, "domain must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check user role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Delete exception from database
+	if err := db.DeleteBlocklistException(r.URL.Path[len(path):], database); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to delete exception: "+err.Error())
+		return
+	}
+
+	// Start blocking the domain again
+	if err := blocker.Load(); err != nil {
+		log.Printf("Failed to reload blocklists: %v", err)
+	}
+
+	util.Responses.Success(w)
+}
@@ -0,0 +1,69 @@
+package blocklist
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strings"
+)
+
+// Handle requests regarding blocklists
+func AllBlocklistsHandler(blocker *Blocker, db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			list(w, r, blocker, db)
+			return
+		case "POST":
+			create(w, r, blocker, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests for methods regarding singular blocklists and the exceptions to all of them
+func SingleBlocklistHandler(path string, blocker *Blocker, db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Exceptions are managed under their own path
+		if r.URL.Path[len(path):] == "exceptions" || strings.HasPrefix(r.URL.Path[len(path):], "exceptions/") {
+			exceptionsHandler(w, r, path+"exceptions", blocker, db)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			read(w, r, path, blocker, db)
+			return
+		case "PUT":
+			update(w, r, path, blocker, db)
+			return
+		case "DELETE":
+			deleteBlocklist(w, r, path, blocker, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests regarding exceptions
+func exceptionsHandler(w http.ResponseWriter, r *http.Request, path string, blocker *Blocker, db *bolt.DB) {
+	switch r.Method {
+	case "GET":
+		listExceptions(w, r, db)
+		return
+	case "POST":
+		addException(w, r, blocker, db)
+		return
+	case "DELETE":
+		deleteException(w, r, path+"/", blocker, db)
+		return
+	default:
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+}
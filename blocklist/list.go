@@ -0,0 +1,46 @@
+package blocklist
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+// A list along with how it was loaded and how many queries it blocked
+type listWithStats struct {
+	db.Blocklist
+	Stats
+}
+
+// Handle the listing of all blocklists
+func list(w http.ResponseWriter, r *http.Request, blocker *Blocker, database *bolt.DB) {
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	if _, err := db.TokenFromString(r.Header.Get("Authorization"), database); err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	lists, err := db.ListBlocklists(database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve blocklists: "+err.Error())
+		return
+	}
+
+	stats := blocker.Stats()
+	all := []listWithStats{}
+	for _, l := range lists {
+		all = append(all, listWithStats{Blocklist: l, Stats: stats[l.Name]})
+	}
+
+	util.Responses.SuccessWithData(w, all)
+}
@@ -0,0 +1,151 @@
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/miekg/dns"
+	"io"
+	"net"
+	"strings"
+)
+
+// Formats lists can be written in
+const (
+	Hosts   = "hosts"
+	Domains = "domains"
+	RPZ     = "rpz"
+)
+
+// Names commonly mapped by hosts files, which must keep working
+var localNames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+}
+
+// A single blocked name and what to answer it with.
+// An empty action means the list or default action is used.
+type entry struct {
+	name      string
+	wildcard  bool
+	action    string
+	addresses []net.IP
+	allow     bool
+}
+
+// Parse a list in one of the supported formats
+func parse(r io.Reader, format, file string) ([]entry, error) {
+	switch format {
+	case Hosts:
+		return parseLines(r, func(fields []string) []entry {
+			// Entries are an address followed by one or more names
+			if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+				return nil
+			}
+
+			var entries []entry
+			for _, name := range fields[1:] {
+				entries = append(entries, entry{name: name})
+			}
+			return entries
+		})
+	case Domains:
+		return parseLines(r, func(fields []string) []entry {
+			return []entry{{name: fields[0]}}
+		})
+	case RPZ:
+		return parseRPZ(r, file)
+	}
+	return nil, fmt.Errorf("unknown blocklist format '%s'", format)
+}
+
+// Parse a list with entries given per line, skipping comments
+func parseLines(r io.Reader, parseFields func([]string) []entry) ([]entry, error) {
+	var entries []entry
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#!"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		for _, e := range parseFields(fields) {
+			e.name = normalize(e.name)
+			if e.name == "" || localNames[e.name] {
+				continue
+			} else if _, ok := dns.IsDomainName(e.name); !ok {
+				continue
+			}
+
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// Parse a response policy zone. Policies are given as CNAMEs to '.' for NXDOMAIN, '*.' for NODATA,
+// and 'rpz-passthru.' for exceptions, while address records give the addresses to answer with.
+func parseRPZ(r io.Reader, file string) ([]entry, error) {
+	var entries []entry
+	var origin string
+
+	// Policy zones without an origin use names relative to the root
+	zp := dns.NewZoneParser(r, ".", file)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if soa, isSOA := rr.(*dns.SOA); isSOA && origin == "" {
+			origin = strings.ToLower(soa.Hdr.Name)
+			continue
+		}
+
+		// Owner names are relative to the policy zone
+		name := strings.ToLower(rr.Header().Name)
+		if origin != "" && origin != "." && strings.HasSuffix(name, "."+origin) {
+			name = strings.TrimSuffix(name, "."+origin)
+		}
+		e := entry{name: normalize(name)}
+		if strings.HasPrefix(e.name, "*.") {
+			e.name = e.name[2:]
+			e.wildcard = true
+		}
+
+		switch record := rr.(type) {
+		case *dns.CNAME:
+			switch strings.ToLower(record.Target) {
+			case ".":
+				e.action = NXDomain
+			case "*.":
+				e.action = NoData
+			case "rpz-passthru.":
+				e.allow = true
+			default:
+				e.action = NXDomain
+			}
+		case *dns.A:
+			e.action = Sinkhole
+			e.addresses = []net.IP{record.A}
+		case *dns.AAAA:
+			e.action = Sinkhole
+			e.addresses = []net.IP{record.AAAA}
+		default:
+			continue
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, zp.Err()
+}
+
+// Convert a name to how entries are matched, lowercase without the trailing dot
+func normalize(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
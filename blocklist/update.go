@@ -0,0 +1,108 @@
+package blocklist
+
+import (
+	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net/http"
+)
+
+func update(w http.ResponseWriter, r *http.Request, path string, blocker *Blocker, database *bolt.DB) {
+	// Validate initial request with type, body exists, and headers
+	if r.Method != "PUT" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "blocklist must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check user role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, valid := util.ValidateBody(body, []string{"file", "format", "action"}, map[string]map[string]string{
+		"file": {"type": "string", "required": "false"},
+		"format": {"type": "string", "required": "false"},
+		"action": {"type": "string", "required": "false"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	// Get list from database
+	l, err := db.GetBlocklist(r.URL.Path[len(path):], database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve blocklist: "+err.Error())
+		return
+	} else if l == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "specified blocklist does not exist")
+		return
+	}
+
+	// Update values if they exist in the body
+	if valid["file"] {
+		l.File = body["file"].(string)
+	}
+	if valid["format"] {
+		l.Format = body["format"].(string)
+	}
+	if valid["action"] {
+		l.Action = body["action"].(string)
+	}
+
+	if !ValidFormat(l.Format) {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'format' must be one of 'hosts', 'domains', or 'rpz'")
+		return
+	} else if l.Action != "" && !ValidAction(l.Action) {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'action' must be one of 'nxdomain', 'nodata', or 'sinkhole'")
+		return
+	}
+
+	// Save to database
+	if err := l.Encode(database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write blocklist to database: "+err.Error())
+		return
+	}
+
+	// Block using the updated list
+	if err := blocker.Load(); err != nil {
+		log.Printf("Failed to reload blocklists: %v", err)
+	}
+
+	util.Responses.Success(w)
+}
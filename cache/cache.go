@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"container/list"
+	"github.com/miekg/dns"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A size limited cache of upstream responses shared between all listeners
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	hits    uint64
+	misses  uint64
+}
+
+// A cached response along with when it was stored and when it expires
+type entry struct {
+	key     string
+	msg     *dns.Msg
+	stored  time.Time
+	expires time.Time
+}
+
+// Hit and miss counters of a cache
+type Stats struct {
+	Size   int    `json:"size"`
+	Limit  int    `json:"limit"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// Create a cache holding at most size responses, where 0 disables caching
+func New(size int) *Cache {
+	return &Cache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Build the key a question is stored under
+func key(name string, qtype, qclass uint16) string {
+	return strings.ToLower(dns.Fqdn(name)) + "/" + dns.Type(qtype).String() + "/" + dns.Class(qclass).String()
+}
+
+// Build the key a response is stored under for the clients within a subnet.
+// Responses with a scope of 0 apply to every client and use the key of the question.
+func subnetKey(question string, subnet *dns.EDNS0_SUBNET, scope uint8) string {
+	if subnet == nil || scope == 0 {
+		return question
+	}
+
+	bits := 32
+	if subnet.Family == 2 {
+		bits = 128
+	}
+	return question + "/" + subnet.Address.Mask(net.CIDRMask(int(scope), bits)).String() + "/" + strconv.Itoa(int(scope))
+}
+
+// Retrieve a response with its TTLs reduced by the time it has been cached, nil if not found or expired.
+// With a client subnet, the response with the most specific scope covering the subnet is used.
+func (c *Cache) Get(name string, qtype, qclass uint16, subnet *dns.EDNS0_SUBNET) *dns.Msg {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	question := key(name, qtype, qclass)
+	var element *list.Element
+	found := false
+	for scope := int(sourcePrefix(subnet)); scope >= 0 && !found; scope-- {
+		element, found = c.entries[subnetKey(question, subnet, uint8(scope))]
+	}
+	if !found {
+		c.misses++
+		return nil
+	}
+
+	e := element.Value.(*entry)
+	if !time.Now().Before(e.expires) {
+		c.order.Remove(element)
+		delete(c.entries, e.key)
+		c.misses++
+		return nil
+	}
+
+	c.hits++
+	c.order.MoveToFront(element)
+
+	// Copy so callers cannot change the stored response
+	msg := e.msg.Copy()
+	elapsed := uint32(time.Since(e.stored) / time.Second)
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			} else if rr.Header().Ttl > elapsed {
+				rr.Header().Ttl -= elapsed
+			} else {
+				rr.Header().Ttl = 0
+			}
+		}
+	}
+
+	return msg
+}
+
+// Store a response for as long as its TTLs allow, evicting the least recently used response if full.
+// Responses to queries with a client subnet are stored for the scope the response is valid for.
+func (c *Cache) Set(msg *dns.Msg, subnet *dns.EDNS0_SUBNET) {
+	if c.size <= 0 || len(msg.Question) == 0 {
+		return
+	}
+
+	ttl, ok := cacheTTL(msg)
+	if !ok || ttl == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Responses without a client subnet apply to every client
+	var scope uint8
+	if opt := msg.IsEdns0(); subnet != nil && opt != nil {
+		for _, option := range opt.Option {
+			if ecs, ok := option.(*dns.EDNS0_SUBNET); ok {
+				scope = ecs.SourceScope
+			}
+		}
+	}
+
+	// Scopes longer than the subnet sent cannot be told apart
+	if scope > sourcePrefix(subnet) {
+		scope = sourcePrefix(subnet)
+	}
+
+	now := time.Now()
+	e := &entry{
+		key:     subnetKey(key(msg.Question[0].Name, msg.Question[0].Qtype, msg.Question[0].Qclass), subnet, scope),
+		msg:     msg.Copy(),
+		stored:  now,
+		expires: now.Add(time.Duration(ttl) * time.Second),
+	}
+
+	// Replace existing responses for the same question
+	if element, ok := c.entries[e.key]; ok {
+		element.Value = e
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[e.key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// Get the prefix length of a client subnet, 0 if there is none
+func sourcePrefix(subnet *dns.EDNS0_SUBNET) uint8 {
+	if subnet == nil {
+		return 0
+	}
+	return subnet.SourceNetmask
+}
+
+// Get the hit and miss counters of the cache
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Size:   c.order.Len(),
+		Limit:  c.size,
+		Hits:   c.hits,
+		Misses: c.misses,
+	}
+}
+
+// Get how long a response can be cached for and whether it can be cached at all.
+// Answers last as long as their lowest TTL, negative answers as long as the SOA minimum allows.
+func cacheTTL(msg *dns.Msg) (uint32, bool) {
+	if msg.Rcode != dns.RcodeSuccess && msg.Rcode != dns.RcodeNameError {
+		return 0, false
+	} else if msg.Truncated {
+		return 0, false
+	}
+
+	if msg.Rcode == dns.RcodeSuccess && len(msg.Answer) != 0 {
+		ttl := msg.Answer[0].Header().Ttl
+		for _, rr := range msg.Answer {
+			if rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+			}
+		}
+		return ttl, true
+	}
+
+	// Negative answers without an SOA cannot be cached
+	for _, rr := range msg.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			if soa.Minttl < soa.Hdr.Ttl {
+				return soa.Minttl, true
+			}
+			return soa.Hdr.Ttl, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+// Handle requests regarding the cache
+func StatsHandler(c *Cache, db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			stats(w, r, c, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+func stats(w http.ResponseWriter, r *http.Request, c *Cache, database *bolt.DB) {
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	if _, err := db.TokenFromString(r.Header.Get("Authorization"), database); err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, c.Stats())
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+)
+
+// A list of domains to block, loaded from a local file
+type Blocklist struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Format string `json:"format"`
+	Action string `json:"action"`
+}
+
+func (b *Blocklist) Encode(db *bolt.DB) error {
+	j, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("blocklists")).Put([]byte(b.Name), j)
+	})
+}
+
+func GetBlocklist(name string, db *bolt.DB) (*Blocklist, error) {
+	var b *Blocklist
+
+	if err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("blocklists")).Get([]byte(name)); len(value) != 0 {
+			b = &Blocklist{}
+			return json.Unmarshal(value, b)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func ListBlocklists(db *bolt.DB) ([]Blocklist, error) {
+	lists := []Blocklist{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("blocklists")).ForEach(func(k, v []byte) error {
+			var b Blocklist
+			if err := json.Unmarshal(v, &b); err != nil {
+				return err
+			}
+
+			lists = append(lists, b)
+			return nil
+		})
+	})
+
+	return lists, err
+}
+
+func DeleteBlocklist(name string, db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		lists := tx.Bucket([]byte("blocklists"))
+
+		if value := lists.Get([]byte(name)); len(value) == 0 {
+			return fmt.Errorf("blocklist does not exist")
+		}
+		return lists.Delete([]byte(name))
+	})
+}
+
+// Exceptions are domains that are never blocked, stored lowercase without the trailing dot
+
+func AddBlocklistException(domain string, db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("exceptions")).Put([]byte(NormalizeZone(domain)), []byte{1})
+	})
+}
+
+func ListBlocklistExceptions(db *bolt.DB) ([]string, error) {
+	exceptions := []string{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("exceptions")).ForEach(func(k, v []byte) error {
+			exceptions = append(exceptions, string(k))
+			return nil
+		})
+	})
+
+	return exceptions, err
+}
+
+func DeleteBlocklistException(domain string, db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		exceptions := tx.Bucket([]byte("exceptions"))
+
+		if value := exceptions.Get([]byte(NormalizeZone(domain))); len(value) == 0 {
+			return fmt.Errorf("exception does not exist")
+		}
+		return exceptions.Delete([]byte(NormalizeZone(domain)))
+	})
+}
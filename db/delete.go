@@ -1,248 +1,133 @@
 package db
 
 import (
+	"fmt"
 	bolt "go.etcd.io/bbolt"
 )
 
-func (d deleteRecord) A(qname string) error {
-	return  d.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("A")).Delete([]byte(qname))
+// Deleting removes a single member of a record set by its id, or the entire set if the id is empty
+
+// Delete an entire record set of any type
+func (d deleteRecord) Set(recordType, qname string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, d.view, recordType)
+		if records == nil {
+			return fmt.Errorf("unsupported record type '%s'", recordType)
+		}
+		return removeMembers(tx, d.view, recordType, qname, "")
 	})
 }
 
-func (d deleteRecord) AAAA(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("AAAA")).Delete([]byte(qname))
+func (d deleteRecord) A(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "A", qname, id)
 	})
 }
 
-func (d deleteRecord) CNAME(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("CNAME")).Delete([]byte(qname))
+func (d deleteRecord) AAAA(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "AAAA", qname, id)
 	})
 }
 
-func (d deleteRecord) MX(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("MX"))
-
-		if err := records.Delete([]byte(qname + "*host")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*priority"))
+func (d deleteRecord) CNAME(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "CNAME", qname, id)
 	})
 }
 
-func (d deleteRecord) LOC(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("LOC"))
-
-		if err := records.Delete([]byte(qname + "*version")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*size")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*horiz")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*vert")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*lat")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*long")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*alt"))
+func (d deleteRecord) MX(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "MX", qname, id)
 	})
 }
 
-func (d deleteRecord) SRV(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SRV"))
-
-		if err := records.Delete([]byte(qname + "*priority")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*weight")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*port")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*target"))
+func (d deleteRecord) LOC(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "LOC", qname, id)
 	})
 }
 
-func (d deleteRecord) SPF(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("SPF")).Delete([]byte(qname))
+func (d deleteRecord) SRV(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "SRV", qname, id)
 	})
 }
 
-func (d deleteRecord) TXT(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("TXT")).Delete([]byte(qname))
+func (d deleteRecord) SPF(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "SPF", qname, id)
 	})
 }
 
-func (d deleteRecord) NS(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("NS")).Delete([]byte(qname))
+func (d deleteRecord) TXT(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "TXT", qname, id)
 	})
 }
 
-func (d deleteRecord) CAA(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("CAA"))
-
-		if err := records.Delete([]byte(qname + "*tag")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*content"))
+func (d deleteRecord) NS(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "NS", qname, id)
 	})
 }
 
-func (d deleteRecord) PTR(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("PTR")).Delete([]byte(qname))
+func (d deleteRecord) CAA(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "CAA", qname, id)
 	})
 }
 
-func (d deleteRecord) CERT(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("CERT"))
-
-		if err := records.Delete([]byte(qname + "*type")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*keytag")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*algorithm")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*certificate"))
+func (d deleteRecord) PTR(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "PTR", qname, id)
 	})
 }
 
-func (d deleteRecord) DNSKEY(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("DNSKEY"))
-
-		if err := records.Delete([]byte(qname + "*flags")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*protocol")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*algorithm")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*publickey"))
+func (d deleteRecord) CERT(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "CERT", qname, id)
 	})
 }
 
-func (d deleteRecord) DS(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("DS"))
-
-		if err := records.Delete([]byte(qname + "*keytag")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*algorithm")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*digesttype")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*digest"))
+func (d deleteRecord) DNSKEY(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "DNSKEY", qname, id)
 	})
 }
 
-func (d deleteRecord) NAPTR(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("NAPTR"))
-
-		if err := records.Delete([]byte(qname + "*order")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*preference")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*flags")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*service")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*regexp")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*replacement"))
+func (d deleteRecord) DS(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "DS", qname, id)
 	})
 }
 
-func (d deleteRecord) SMIMEA(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SMIMEA"))
-
-		if err := records.Delete([]byte(qname + "*usage")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*selector")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*matching")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*certificate"))
+func (d deleteRecord) NAPTR(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "NAPTR", qname, id)
 	})
 }
 
-func (d deleteRecord) SSHFP(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SSHFP"))
-
-		if err := records.Delete([]byte(qname + "*algorithm")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*type")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*fingerprint"))
+func (d deleteRecord) SMIMEA(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "SMIMEA", qname, id)
 	})
 }
 
-func (d deleteRecord) TLSA(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("TLSA"))
-
-		if err := records.Delete([]byte(qname + "*usage")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*selector")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*matching")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*certificate"))
+func (d deleteRecord) SSHFP(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "SSHFP", qname, id)
 	})
 }
 
-func (d deleteRecord) URI(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("URI"))
+func (d deleteRecord) TLSA(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "TLSA", qname, id)
+	})
+}
 
-		if err := records.Delete([]byte(qname + "*priority")); err != nil {
-			return err
-		}
-		if err := records.Delete([]byte(qname + "*weight")); err != nil {
-			return err
-		}
-		return records.Delete([]byte(qname + "*target"))
+func (d deleteRecord) URI(qname, id string) error {
+	return d.write(func(tx *bolt.Tx) error {
+		return removeMembers(tx, d.view, "URI", qname, id)
 	})
 }
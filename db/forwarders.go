@@ -0,0 +1,53 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Upstream resolvers managed through the API for names under a domain
+type ForwardingRule struct {
+	Domain   string   `json:"domain"`
+	Upstream []string `json:"upstream"`
+}
+
+func (f *ForwardingRule) Encode(db *bolt.DB) error {
+	j, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("forwarders")).Put([]byte(NormalizeZone(f.Domain)), j)
+	})
+}
+
+func ListForwardingRules(db *bolt.DB) ([]ForwardingRule, error) {
+	rules := []ForwardingRule{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("forwarders")).ForEach(func(k, v []byte) error {
+			var f ForwardingRule
+			if err := json.Unmarshal(v, &f); err != nil {
+				return err
+			}
+
+			rules = append(rules, f)
+			return nil
+		})
+	})
+
+	return rules, err
+}
+
+func DeleteForwardingRule(domain string, db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		rules := tx.Bucket([]byte("forwarders"))
+
+		if value := rules.Get([]byte(NormalizeZone(domain))); len(value) == 0 {
+			return fmt.Errorf("forwarding rule does not exist")
+		}
+		return rules.Delete([]byte(NormalizeZone(domain)))
+	})
+}
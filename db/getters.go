@@ -6,541 +6,675 @@ import (
 	bolt "go.etcd.io/bbolt"
 	"log"
 	"net"
+	"strings"
 )
 
-func (g get) A(qname string) *A {
-	a := &A{}
+// Check if a name has records of any type
+func (g get) Exists(qname string) bool {
+	found := false
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("A"))
+	if err := g.read(func(tx *bolt.Tx) error {
+		for _, recordType := range RecordTypes {
+			if exists(Bucket(tx, g.view, recordType), qname[:len(qname)-1]) {
+				found = true
+				return nil
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Printf("Failed to check if records exist for '%s': %v", qname, err)
+		return false
+	}
+	return found
+}
+
+// Check if a name exists, either with records of its own or as an ancestor of another name
+func (g get) NameExists(qname string) bool {
+	found := false
+
+	if err := g.read(func(tx *bolt.Tx) error {
+		found = nameExists(tx, g.view, qname[:len(qname)-1])
+		return nil
+	}); err != nil {
+		log.Printf("Failed to check if '%s' exists: %v", qname, err)
+		return false
+	}
+	return found
+}
+
+// Find the wildcard that answers for a name that does not exist, following RFC 4592.
+// Returns an empty string if the name exists or no wildcard applies.
+func (g get) Wildcard(qname string) string {
+	var wildcard string
+
+	if err := g.read(func(tx *bolt.Tx) error {
+		name := qname[:len(qname)-1]
+		if nameExists(tx, g.view, name) {
+			return nil
+		}
+
+		// Only the wildcard directly below the closest existing ancestor can match
+		for i := strings.Index(name, "."); i != -1; i = strings.Index(name, ".") {
+			name = name[i+1:]
+			if !nameExists(tx, g.view, name) {
+				continue
+			}
+
+			if nameExists(tx, g.view, "*."+name) {
+				wildcard = "*." + name + "."
+			}
+			return nil
+		}
+
+		return nil
+	}); err != nil {
+		log.Printf("Failed to find wildcard for '%s': %v", qname, err)
+		return ""
+	}
+	return wildcard
+}
 
-		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
-			a.Address = net.ParseIP(string(value))
+func (g get) A(qname string) []A {
+	var set []A
+
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "A"), qname[:len(qname)-1])
+
+		for _, id := range ids {
+			if value := fields[id]["host"]; len(value) != 0 {
+				set = append(set, A{ID: id, TTL: readTTL(fields[id]), Address: net.ParseIP(string(value))})
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve A record for '%s': %v", qname, err)
-        return nil
-	} else if len(a.Address) == 0 {
 		return nil
 	}
-	return a
+	return set
 }
 
-func (g get) AAAA(qname string) *AAAA {
-	a := &AAAA{}
+func (g get) AAAA(qname string) []AAAA {
+	var set []AAAA
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("AAAA"))
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "AAAA"), qname[:len(qname)-1])
 
-		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
-			a.Address = net.ParseIP(string(value))
+		for _, id := range ids {
+			if value := fields[id]["host"]; len(value) != 0 {
+				set = append(set, AAAA{ID: id, TTL: readTTL(fields[id]), Address: net.ParseIP(string(value))})
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve AAAA record for '%s': %v", qname, err)
-        return nil
-	} else if len(a.Address) == 0 {
 		return nil
 	}
-	return a
+	return set
 }
 
-func (g get) CNAME(qname string) *CNAME {
-	c := &CNAME{}
+func (g get) CNAME(qname string) []CNAME {
+	var set []CNAME
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("CNAME"))
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "CNAME"), qname[:len(qname)-1])
 
-		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
-			c.Target = string(value)
+		for _, id := range ids {
+			if value := fields[id]["target"]; len(value) != 0 {
+				set = append(set, CNAME{ID: id, TTL: readTTL(fields[id]), Target: string(value)})
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve CNAME record for '%s': %v", qname, err)
-        return nil
-	} else if len(c.Target) == 0 {
 		return nil
 	}
-	return c
+	return set
 }
 
-func (g get) MX(qname string) *MX {
-	m := &MX{}
+func (g get) MX(qname string) []MX {
+	var set []MX
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("MX"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "MX"), qname[:len(qname)-1])
 
-		if hostValue := records.Get([]byte(shortenedName + "*host")); len(hostValue) != 0 {
-			m.Host = string(hostValue)
-		}
-		if priorityValue := records.Get([]byte(shortenedName + "*priority")); len(priorityValue) != 0 {
-			m.Priority = binary.BigEndian.Uint16(priorityValue)
+		for _, id := range ids {
+			m := MX{ID: id, TTL: readTTL(fields[id])}
+
+			if hostValue := fields[id]["host"]; len(hostValue) != 0 {
+				m.Host = string(hostValue)
+			}
+			if priorityValue := fields[id]["priority"]; len(priorityValue) != 0 {
+				m.Priority = binary.BigEndian.Uint16(priorityValue)
+			}
+
+			if len(m.Host) != 0 {
+				set = append(set, m)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve MX record for '%s': %v", qname, err)
-        return nil
-	} else if len(m.Host) == 0 {
 		return nil
 	}
-	return m
+	return set
 }
 
-func (g get) LOC(qname string) *LOC {
-	l := &LOC{}
+func (g get) LOC(qname string) []LOC {
+	var set []LOC
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("LOC"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "LOC"), qname[:len(qname)-1])
 
-		if versionValue := records.Get([]byte(shortenedName + "*version")); len(versionValue) != 0 {
-			l.Version = versionValue[0]
-		}
-		if sizeValue := records.Get([]byte(shortenedName + "*size")); len(sizeValue) != 0 {
-			l.Size = sizeValue[0]
-		}
-		if horizValue := records.Get([]byte(shortenedName + "*horiz")); len(horizValue) != 0 {
-			l.HorizontalPrecision = horizValue[0]
-		}
-		if vertValue := records.Get([]byte(shortenedName + "*vert")); len(vertValue) != 0 {
-			l.VerticalPrecision = vertValue[0]
-		}
-		if altValue := records.Get([]byte(shortenedName + "*alt")); len(altValue) != 0 {
-			l.Altitude = binary.BigEndian.Uint32(altValue)
-		}
-		if latDegValue := records.Get([]byte(shortenedName + "*lat-degrees")); len(latDegValue) != 0 {
-			l.LatDegrees = latDegValue[0]
-		}
-		if latMinValue := records.Get([]byte(shortenedName + "*lat-minutes")); len(latMinValue) != 0 {
-			l.LatMinutes = latMinValue[0]
-		}
-		if latSecValue := records.Get([]byte(shortenedName + "*lat-seconds")); len(latSecValue) != 0 {
-			l.LatSeconds = latSecValue[0]
-		}
-		if latDirValue := records.Get([]byte(shortenedName + "*lat-direction")); len(latDirValue) != 0 {
-			l.LatDirection = string(latDirValue)
-		}
-		if longDegValue := records.Get([]byte(shortenedName + "*long-degrees")); len(longDegValue) != 0 {
-			l.LatDegrees = longDegValue[0]
-		}
-		if longMinValue := records.Get([]byte(shortenedName + "*long-minutes")); len(longMinValue) != 0 {
-			l.LatMinutes = longMinValue[0]
-		}
-		if longSecValue := records.Get([]byte(shortenedName + "*long-seconds")); len(longSecValue) != 0 {
-			l.LatSeconds = longSecValue[0]
-		}
-		if longDirValue := records.Get([]byte(shortenedName + "*long-direction")); len(longDirValue) != 0 {
-			l.LongDirection = string(longDirValue)
+		for _, id := range ids {
+			l := LOC{ID: id, TTL: readTTL(fields[id])}
+
+			if versionValue := fields[id]["version"]; len(versionValue) != 0 {
+				l.Version = versionValue[0]
+			}
+			if sizeValue := fields[id]["size"]; len(sizeValue) != 0 {
+				l.Size = sizeValue[0]
+			}
+			if horizValue := fields[id]["horiz"]; len(horizValue) != 0 {
+				l.HorizontalPrecision = horizValue[0]
+			}
+			if vertValue := fields[id]["vert"]; len(vertValue) != 0 {
+				l.VerticalPrecision = vertValue[0]
+			}
+			if altValue := fields[id]["alt"]; len(altValue) != 0 {
+				l.Altitude = binary.BigEndian.Uint32(altValue)
+			}
+			if latDegValue := fields[id]["lat-degrees"]; len(latDegValue) != 0 {
+				l.LatDegrees = latDegValue[0]
+			}
+			if latMinValue := fields[id]["lat-minutes"]; len(latMinValue) != 0 {
+				l.LatMinutes = latMinValue[0]
+			}
+			if latSecValue := fields[id]["lat-seconds"]; len(latSecValue) != 0 {
+				l.LatSeconds = latSecValue[0]
+			}
+			if latDirValue := fields[id]["lat-direction"]; len(latDirValue) != 0 {
+				l.LatDirection = string(latDirValue)
+			}
+			if longDegValue := fields[id]["long-degrees"]; len(longDegValue) != 0 {
+				l.LongDegrees = longDegValue[0]
+			}
+			if longMinValue := fields[id]["long-minutes"]; len(longMinValue) != 0 {
+				l.LongMinutes = longMinValue[0]
+			}
+			if longSecValue := fields[id]["long-seconds"]; len(longSecValue) != 0 {
+				l.LongSeconds = longSecValue[0]
+			}
+			if longDirValue := fields[id]["long-direction"]; len(longDirValue) != 0 {
+				l.LongDirection = string(longDirValue)
+			}
+
+			if len(l.LongDirection) != 0 || len(l.LatDirection) != 0 {
+				set = append(set, l)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve LOC record for '%s': %v", qname, err)
-        return nil
-	} else if len(l.LongDirection) == 0 && len(l.LatDirection) == 0 {
 		return nil
 	}
-	return l
+	return set
 }
 
-func (g get) SRV(qname string) *SRV {
-	s := &SRV{}
+func (g get) SRV(qname string) []SRV {
+	var set []SRV
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SRV"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "SRV"), qname[:len(qname)-1])
 
-		if priorityValue := records.Get([]byte(shortenedName + "*priority")); len(priorityValue) != 0 {
-			s.Priority = binary.BigEndian.Uint16(priorityValue)
-		}
-		if weightValue := records.Get([]byte(shortenedName + "*weight")); len(weightValue) != 0 {
-			s.Weight = binary.BigEndian.Uint16(weightValue)
-		}
-		if portValue := records.Get([]byte(shortenedName + "*port")); len(portValue) != 0 {
-			s.Port = binary.BigEndian.Uint16(portValue)
-		}
-		if targetValue := records.Get([]byte(shortenedName + "*target")); len(targetValue) != 0 {
-			s.Target = string(targetValue)
+		for _, id := range ids {
+			s := SRV{ID: id, TTL: readTTL(fields[id])}
+
+			if priorityValue := fields[id]["priority"]; len(priorityValue) != 0 {
+				s.Priority = binary.BigEndian.Uint16(priorityValue)
+			}
+			if weightValue := fields[id]["weight"]; len(weightValue) != 0 {
+				s.Weight = binary.BigEndian.Uint16(weightValue)
+			}
+			if portValue := fields[id]["port"]; len(portValue) != 0 {
+				s.Port = binary.BigEndian.Uint16(portValue)
+			}
+			if targetValue := fields[id]["target"]; len(targetValue) != 0 {
+				s.Target = string(targetValue)
+			}
+
+			if len(s.Target) != 0 {
+				set = append(set, s)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve SRV record for '%s': %v", qname, err)
-        return nil
-	} else if len(s.Target) == 0 {
 		return nil
 	}
-	return s
+	return set
 }
 
-func (g get) SPF(qname string) *SPF {
-	var content []string
+func (g get) SPF(qname string) []SPF {
+	var set []SPF
+
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "SPF"), qname[:len(qname)-1])
+
+		for _, id := range ids {
+			var content []string
+			if value := fields[id]["text"]; len(value) != 0 {
+				if err := json.Unmarshal(value, &content); err != nil {
+					return err
+				}
+			}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SPF"))
+			// Prune all empty strings
+			var text []string
+			for _, v := range content {
+				if len(v) != 0 {
+					text = append(text, v)
+				}
+			}
 
-		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
-			if err := json.Unmarshal(value, &content); err != nil {
-				return err
+			if len(text) != 0 {
+				set = append(set, SPF{ID: id, TTL: readTTL(fields[id]), Text: text})
 			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve SPF record for '%s': %v", qname, err)
-        return nil
-	} else if len(content) == 0 {
 		return nil
 	}
+	return set
+}
 
-	// Prune all empty strings
-	var text []string
-	for _, v := range content {
-		if len(v) != 0 {
-			text = append(text, v)
-		}
-	}
+func (g get) TXT(qname string) []TXT {
+	var set []TXT
 
-	return &SPF{Text: content}
-}
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "TXT"), qname[:len(qname)-1])
 
-func (g get) TXT(qname string) *TXT {
-	var content []string
+		for _, id := range ids {
+			var content []string
+			if value := fields[id]["text"]; len(value) != 0 {
+				if err := json.Unmarshal(value, &content); err != nil {
+					return err
+				}
+			}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("TXT"))
+			// Prune all empty strings
+			var text []string
+			for _, v := range content {
+				if len(v) != 0 {
+					text = append(text, v)
+				}
+			}
 
-		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
-			if err := json.Unmarshal(value, &content); err != nil {
-				return err
+			if len(text) != 0 {
+				set = append(set, TXT{ID: id, TTL: readTTL(fields[id]), Text: text})
 			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve TXT record for '%s': %v", qname, err)
-        return nil
-	} else if len(content) == 0 {
 		return nil
 	}
-
-	// Prune all empty strings
-	var text []string
-	for _, v := range content {
-		if len(v) != 0 {
-			text = append(text, v)
-		}
-	}
-
-	return &TXT{Text: text}
+	return set
 }
 
-func (g get) NS(qname string) *NS {
-	n := &NS{}
+func (g get) NS(qname string) []NS {
+	var set []NS
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("NS"))
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "NS"), qname[:len(qname)-1])
 
-		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
-			n.Nameserver = string(value)
+		for _, id := range ids {
+			if value := fields[id]["nameserver"]; len(value) != 0 {
+				set = append(set, NS{ID: id, TTL: readTTL(fields[id]), Nameserver: string(value)})
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve NS record for '%s': %v", qname, err)
-        return nil
-	} else if len(n.Nameserver) == 0 {
 		return nil
 	}
-	return n
+	return set
 }
 
-func (g get) CAA(qname string) *CAA {
-	c := &CAA{Flag: 0}
+func (g get) CAA(qname string) []CAA {
+	var set []CAA
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("CAA"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "CAA"), qname[:len(qname)-1])
 
-		if tagValue := records.Get([]byte(shortenedName + "*tag")); len(tagValue) != 0 {
-			c.Tag = string(tagValue)
-		}
-		if contentValue := records.Get([]byte(shortenedName + "*content")); len(contentValue) != 0 {
-			c.Content = string(contentValue)
+		for _, id := range ids {
+			c := CAA{ID: id, TTL: readTTL(fields[id]), Flag: 0}
+
+			if tagValue := fields[id]["tag"]; len(tagValue) != 0 {
+				c.Tag = string(tagValue)
+			}
+			if contentValue := fields[id]["content"]; len(contentValue) != 0 {
+				c.Content = string(contentValue)
+			}
+
+			if len(c.Content) != 0 {
+				set = append(set, c)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve CAA record for '%s': %v", qname, err)
-        return nil
-	} else if len(c.Content) == 0 {
 		return nil
 	}
-	return c
+	return set
 }
 
-func (g get) PTR(qname string) *PTR {
-	p := &PTR{}
+func (g get) PTR(qname string) []PTR {
+	var set []PTR
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("PTR"))
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "PTR"), qname[:len(qname)-1])
 
-		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
-			p.Domain = string(value)
+		for _, id := range ids {
+			if value := fields[id]["domain"]; len(value) != 0 {
+				set = append(set, PTR{ID: id, TTL: readTTL(fields[id]), Domain: string(value)})
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve PTR record for '%s': %v", qname, err)
-        return nil
-	} else if len(p.Domain) == 0 {
 		return nil
 	}
-	return p
+	return set
 }
 
-func (g get) CERT(qname string) *CERT {
-	c := &CERT{}
+func (g get) CERT(qname string) []CERT {
+	var set []CERT
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("CERT"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "CERT"), qname[:len(qname)-1])
 
-		if typeValue := records.Get([]byte(shortenedName + "*type")); len(typeValue) != 0 {
-			c.Type = binary.BigEndian.Uint16(typeValue)
-		}
-		if keyTagValue := records.Get([]byte(shortenedName + "*keytag")); len(keyTagValue) != 0 {
-			c.KeyTag = binary.BigEndian.Uint16(keyTagValue)
-		}
-		if algoValue := records.Get([]byte(shortenedName + "*algorithm")); len(algoValue) != 0 {
-			c.Algorithm = algoValue[0]
-		}
-		if certValue := records.Get([]byte(shortenedName + "*certificate")); len(certValue) != 0 {
-			c.Certificate = string(certValue)
+		for _, id := range ids {
+			c := CERT{ID: id, TTL: readTTL(fields[id])}
+
+			if typeValue := fields[id]["type"]; len(typeValue) != 0 {
+				c.Type = binary.BigEndian.Uint16(typeValue)
+			}
+			if keyTagValue := fields[id]["keytag"]; len(keyTagValue) != 0 {
+				c.KeyTag = binary.BigEndian.Uint16(keyTagValue)
+			}
+			if algoValue := fields[id]["algorithm"]; len(algoValue) != 0 {
+				c.Algorithm = algoValue[0]
+			}
+			if certValue := fields[id]["certificate"]; len(certValue) != 0 {
+				c.Certificate = string(certValue)
+			}
+
+			if len(c.Certificate) != 0 {
+				set = append(set, c)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve CERT record for '%s': %v", qname, err)
-        return nil
-	} else if len(c.Certificate) == 0 {
 		return nil
 	}
-	return c
+	return set
 }
 
-func (g get) DNSKEY(qname string) *DNSKEY {
-	d := &DNSKEY{}
+func (g get) DNSKEY(qname string) []DNSKEY {
+	var set []DNSKEY
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("DNSKEY"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "DNSKEY"), qname[:len(qname)-1])
 
-		if flagsValue := records.Get([]byte(shortenedName + "*flags")); len(flagsValue) != 0 {
-			d.Flags = binary.BigEndian.Uint16(flagsValue)
-		}
-		if protoValue := records.Get([]byte(shortenedName + "*protocol")); len(protoValue) != 0 {
-			d.Protocol = protoValue[0]
-		}
-		if algoValue := records.Get([]byte(shortenedName + "*algorithm")); len(algoValue) != 0 {
-			d.Algorithm = algoValue[0]
-		}
-		if pubValue := records.Get([]byte(shortenedName + "*publickey")); len(pubValue) != 0 {
-			d.PublicKey = string(pubValue)
+		for _, id := range ids {
+			d := DNSKEY{ID: id, TTL: readTTL(fields[id])}
+
+			if flagsValue := fields[id]["flags"]; len(flagsValue) != 0 {
+				d.Flags = binary.BigEndian.Uint16(flagsValue)
+			}
+			if protoValue := fields[id]["protocol"]; len(protoValue) != 0 {
+				d.Protocol = protoValue[0]
+			}
+			if algoValue := fields[id]["algorithm"]; len(algoValue) != 0 {
+				d.Algorithm = algoValue[0]
+			}
+			if pubValue := fields[id]["publickey"]; len(pubValue) != 0 {
+				d.PublicKey = string(pubValue)
+			}
+
+			if len(d.PublicKey) != 0 {
+				set = append(set, d)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve DNSKEY record for '%s': %v", qname, err)
-        return nil
-	} else if len(d.PublicKey) == 0 {
 		return nil
 	}
-	return d
+	return set
 }
 
-func (g get) DS(qname string) *DS {
-	d := &DS{}
+func (g get) DS(qname string) []DS {
+	var set []DS
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("DS"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "DS"), qname[:len(qname)-1])
 
-		if ktagValue := records.Get([]byte(shortenedName + "*keytag")); len(ktagValue) != 0 {
-			d.KeyTag = binary.BigEndian.Uint16(ktagValue)
-		}
-		if algoValue := records.Get([]byte(shortenedName + "*algorithm")); len(algoValue) != 0 {
-			d.Algorithm = algoValue[0]
-		}
-		if dtypeValue := records.Get([]byte(shortenedName + "*digesttype")); len(dtypeValue) != 0 {
-			d.DigestType = dtypeValue[0]
-		}
-		if digestValue := records.Get([]byte(shortenedName + "*digest")); len(digestValue) != 0 {
-			d.Digest = string(digestValue)
+		for _, id := range ids {
+			d := DS{ID: id, TTL: readTTL(fields[id])}
+
+			if ktagValue := fields[id]["keytag"]; len(ktagValue) != 0 {
+				d.KeyTag = binary.BigEndian.Uint16(ktagValue)
+			}
+			if algoValue := fields[id]["algorithm"]; len(algoValue) != 0 {
+				d.Algorithm = algoValue[0]
+			}
+			if dtypeValue := fields[id]["digesttype"]; len(dtypeValue) != 0 {
+				d.DigestType = dtypeValue[0]
+			}
+			if digestValue := fields[id]["digest"]; len(digestValue) != 0 {
+				d.Digest = string(digestValue)
+			}
+
+			if len(d.Digest) != 0 {
+				set = append(set, d)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve DS record for '%s': %v", qname, err)
-        return nil
-	} else if len(d.Digest) == 0 {
 		return nil
 	}
-	return d
+	return set
 }
 
-func (g get) NAPTR(qname string) *NAPTR {
-	n := &NAPTR{}
+func (g get) NAPTR(qname string) []NAPTR {
+	var set []NAPTR
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("NAPTR"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "NAPTR"), qname[:len(qname)-1])
 
-		if orderValue := records.Get([]byte(shortenedName + "*order")); len(orderValue) != 0 {
-			n.Order = binary.BigEndian.Uint16(orderValue)
-		}
-		if prefValue := records.Get([]byte(shortenedName + "*preference")); len(prefValue) != 0 {
-			n.Preference = binary.BigEndian.Uint16(prefValue)
-		}
-		if flagsValue := records.Get([]byte(shortenedName + "*flags")); len(flagsValue) != 0 {
-			n.Flags = string(flagsValue)
-		}
-		if serviceValue := records.Get([]byte(shortenedName + "*service")); len(serviceValue) != 0 {
-			n.Service = string(serviceValue)
-		}
-		if regexpValue := records.Get([]byte(shortenedName + "*regexp")); len(regexpValue) != 0 {
-			n.Regexp = string(regexpValue)
-		}
-		if replacementValue := records.Get([]byte(shortenedName + "*replacement")); len(replacementValue) != 0 {
-			n.Replacement = string(replacementValue)
+		for _, id := range ids {
+			n := NAPTR{ID: id, TTL: readTTL(fields[id])}
+
+			if orderValue := fields[id]["order"]; len(orderValue) != 0 {
+				n.Order = binary.BigEndian.Uint16(orderValue)
+			}
+			if prefValue := fields[id]["preference"]; len(prefValue) != 0 {
+				n.Preference = binary.BigEndian.Uint16(prefValue)
+			}
+			if flagsValue := fields[id]["flags"]; len(flagsValue) != 0 {
+				n.Flags = string(flagsValue)
+			}
+			if serviceValue := fields[id]["service"]; len(serviceValue) != 0 {
+				n.Service = string(serviceValue)
+			}
+			if regexpValue := fields[id]["regexp"]; len(regexpValue) != 0 {
+				n.Regexp = string(regexpValue)
+			}
+			if replacementValue := fields[id]["replacement"]; len(replacementValue) != 0 {
+				n.Replacement = string(replacementValue)
+			}
+
+			if len(n.Replacement) != 0 {
+				set = append(set, n)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve NAPTR record for '%s': %v", qname, err)
-        return nil
-	} else if len(n.Replacement) == 0 {
 		return nil
 	}
-	return n
+	return set
 }
 
-func (g get) SMIMEA(qname string) *SMIMEA {
-	s := &SMIMEA{}
+func (g get) SMIMEA(qname string) []SMIMEA {
+	var set []SMIMEA
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SMIMEA"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "SMIMEA"), qname[:len(qname)-1])
 
-		if usageValue := records.Get([]byte(shortenedName + "*usage")); len(usageValue) != 0 {
-			s.Usage = usageValue[0]
-		}
-		if selectorValue := records.Get([]byte(shortenedName + "*selector")); len(selectorValue) != 0 {
-			s.Selector = selectorValue[0]
-		}
-		if matchingValue := records.Get([]byte(shortenedName + "*matching")); len(matchingValue) != 0 {
-			s.MatchingType = matchingValue[0]
-		}
-		if certValue := records.Get([]byte(shortenedName + "*certificate")); len(certValue) != 0 {
-			s.Certificate = string(certValue)
+		for _, id := range ids {
+			s := SMIMEA{ID: id, TTL: readTTL(fields[id])}
+
+			if usageValue := fields[id]["usage"]; len(usageValue) != 0 {
+				s.Usage = usageValue[0]
+			}
+			if selectorValue := fields[id]["selector"]; len(selectorValue) != 0 {
+				s.Selector = selectorValue[0]
+			}
+			if matchingValue := fields[id]["matching"]; len(matchingValue) != 0 {
+				s.MatchingType = matchingValue[0]
+			}
+			if certValue := fields[id]["certificate"]; len(certValue) != 0 {
+				s.Certificate = string(certValue)
+			}
+
+			if len(s.Certificate) != 0 {
+				set = append(set, s)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve SMIMEA record for '%s': %v", qname, err)
-        return nil
-	} else if len(s.Certificate) == 0 {
 		return nil
 	}
-	return s
+	return set
 }
 
-func (g get) SSHFP(qname string) *SSHFP {
-	s := &SSHFP{}
+func (g get) SSHFP(qname string) []SSHFP {
+	var set []SSHFP
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SSHFP"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "SSHFP"), qname[:len(qname)-1])
 
-		if algorithmValue := records.Get([]byte(shortenedName + "*algorithm")); len(algorithmValue) != 0 {
-			s.Algorithm = algorithmValue[0]
-		}
-		if typeValue := records.Get([]byte(shortenedName + "*type")); len(typeValue) != 0 {
-			s.Type = typeValue[0]
-		}
-		if fingerprintValue := records.Get([]byte(shortenedName + "*fingerprint")); len(fingerprintValue) != 0 {
-			s.Fingerprint = string(fingerprintValue)
+		for _, id := range ids {
+			s := SSHFP{ID: id, TTL: readTTL(fields[id])}
+
+			if algorithmValue := fields[id]["algorithm"]; len(algorithmValue) != 0 {
+				s.Algorithm = algorithmValue[0]
+			}
+			if typeValue := fields[id]["type"]; len(typeValue) != 0 {
+				s.Type = typeValue[0]
+			}
+			if fingerprintValue := fields[id]["fingerprint"]; len(fingerprintValue) != 0 {
+				s.Fingerprint = string(fingerprintValue)
+			}
+
+			if len(s.Fingerprint) != 0 {
+				set = append(set, s)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve SSHFP record for '%s': %v", qname, err)
-        return nil
-	} else if len(s.Fingerprint) == 0 {
 		return nil
 	}
-	return s
+	return set
 }
 
-func (g get) TLSA(qname string) *TLSA {
-	t := &TLSA{}
+func (g get) TLSA(qname string) []TLSA {
+	var set []TLSA
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("TLSA"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "TLSA"), qname[:len(qname)-1])
 
-		if usageValue := records.Get([]byte(shortenedName + "*usage")); len(usageValue) != 0 {
-			t.Usage = usageValue[0]
-		}
-		if selectorValue := records.Get([]byte(shortenedName + "*selector")); len(selectorValue) != 0 {
-			t.Selector = selectorValue[0]
-		}
-		if matchingValue := records.Get([]byte(shortenedName + "*matching")); len(matchingValue) != 0 {
-			t.MatchingType = matchingValue[0]
-		}
-		if certificateValue := records.Get([]byte(shortenedName + "*certificate")); len(certificateValue) != 0 {
-			t.Certificate = string(certificateValue)
+		for _, id := range ids {
+			t := TLSA{ID: id, TTL: readTTL(fields[id])}
+
+			if usageValue := fields[id]["usage"]; len(usageValue) != 0 {
+				t.Usage = usageValue[0]
+			}
+			if selectorValue := fields[id]["selector"]; len(selectorValue) != 0 {
+				t.Selector = selectorValue[0]
+			}
+			if matchingValue := fields[id]["matching"]; len(matchingValue) != 0 {
+				t.MatchingType = matchingValue[0]
+			}
+			if certificateValue := fields[id]["certificate"]; len(certificateValue) != 0 {
+				t.Certificate = string(certificateValue)
+			}
+
+			if len(t.Certificate) != 0 {
+				set = append(set, t)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve TLSA record for '%s': %v", qname, err)
-        return nil
-	} else if len(t.Certificate) == 0 {
 		return nil
 	}
-	return t
+	return set
 }
 
-func (g get) URI(qname string) *URI {
-	u := &URI{}
+func (g get) URI(qname string) []URI {
+	var set []URI
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("URI"))
-		shortenedName := qname[:len(qname)-1]
+	if err := g.read(func(tx *bolt.Tx) error {
+		ids, fields := members(Bucket(tx, g.view, "URI"), qname[:len(qname)-1])
 
-		if priorityValue := records.Get([]byte(shortenedName + "*priority")); len(priorityValue) != 0 {
-			u.Priority = binary.BigEndian.Uint16(priorityValue)
-		}
-		if weightValue := records.Get([]byte(shortenedName + "*weight")); len(weightValue) != 0 {
-			u.Weight = binary.BigEndian.Uint16(weightValue)
-		}
-		if targetValue := records.Get([]byte(shortenedName + "*target")); len(targetValue) != 0 {
-			u.Target = string(targetValue)
+		for _, id := range ids {
+			u := URI{ID: id, TTL: readTTL(fields[id])}
+
+			if priorityValue := fields[id]["priority"]; len(priorityValue) != 0 {
+				u.Priority = binary.BigEndian.Uint16(priorityValue)
+			}
+			if weightValue := fields[id]["weight"]; len(weightValue) != 0 {
+				u.Weight = binary.BigEndian.Uint16(weightValue)
+			}
+			if targetValue := fields[id]["target"]; len(targetValue) != 0 {
+				u.Target = string(targetValue)
+			}
+
+			if len(u.Target) != 0 {
+				set = append(set, u)
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve URI record for '%s': %v", qname, err)
-        return nil
-	} else if len(u.Target) == 0 {
 		return nil
 	}
-	return u
+	return set
 }
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Maximum number of journal entries kept for each zone
+const maxJournalEntries = 100
+
+// The records removed and added when a zone went from one serial to the next
+type JournalEntry struct {
+	From    uint32   `json:"from"`
+	To      uint32   `json:"to"`
+	Deleted []string `json:"deleted"`
+	Added   []string `json:"added"`
+}
+
+// The records of a zone as of a serial, used to find what changed
+type snapshot struct {
+	Serial  uint32   `json:"serial"`
+	Records []string `json:"records"`
+}
+
+// Journal entries are stored as zone*serial, padded so they sort in order
+func journalKey(name string, serial uint32) []byte {
+	return []byte(fmt.Sprintf("%s*%010d", name, serial))
+}
+
+// Record the current records of a zone in presentation format, excluding the SOA.
+// If they changed since last recorded the serial is incremented and the difference journaled.
+func JournalZone(name string, records []string, db *bolt.DB) error {
+	name = NormalizeZone(name)
+
+	return db.Update(func(tx *bolt.Tx) error {
+		zones := tx.Bucket([]byte("zones"))
+		value := zones.Get([]byte(name))
+		if len(value) == 0 {
+			return fmt.Errorf("zone does not exist")
+		}
+
+		var zone Zone
+		if err := json.Unmarshal(value, &zone); err != nil {
+			return err
+		}
+
+		// The first snapshot is the starting point of the journal
+		snapshots := tx.Bucket([]byte("snapshots"))
+		var previous snapshot
+		if value := snapshots.Get([]byte(name)); len(value) == 0 {
+			return putSnapshot(snapshots, name, snapshot{Serial: zone.Serial, Records: records})
+		} else if err := json.Unmarshal(value, &previous); err != nil {
+			return err
+		}
+
+		deleted, added := difference(previous.Records, records)
+		if len(deleted) == 0 && len(added) == 0 && previous.Serial == zone.Serial {
+			return nil
+		}
+
+		// Secondaries need a new serial to notice changed records
+		if previous.Serial == zone.Serial {
+			zone.Serial++
+			encoded, err := json.Marshal(zone)
+			if err != nil {
+				return err
+			}
+			if err := zones.Put([]byte(name), encoded); err != nil {
+				return err
+			}
+		}
+
+		encoded, err := json.Marshal(JournalEntry{From: previous.Serial, To: zone.Serial, Deleted: deleted, Added: added})
+		if err != nil {
+			return err
+		}
+		journal := tx.Bucket([]byte("journal"))
+		if err := journal.Put(journalKey(name, zone.Serial), encoded); err != nil {
+			return err
+		}
+
+		// Drop the oldest entries once there are too many
+		keys := journalKeys(journal, name)
+		for i := 0; i < len(keys)-maxJournalEntries; i++ {
+			if err := journal.Delete(keys[i]); err != nil {
+				return err
+			}
+		}
+
+		return putSnapshot(snapshots, name, snapshot{Serial: zone.Serial, Records: records})
+	})
+}
+
+// Get the journal entries that take a zone from a serial to its latest known serial.
+// Returns nil if the journal does not go back far enough.
+func GetJournal(name string, from uint32, db *bolt.DB) ([]JournalEntry, error) {
+	name = NormalizeZone(name)
+	var entries []JournalEntry
+
+	err := db.View(func(tx *bolt.Tx) error {
+		journal := tx.Bucket([]byte("journal"))
+
+		// Follow entries from the requested serial onwards
+		serial := from
+		for _, k := range journalKeys(journal, name) {
+			var entry JournalEntry
+			if err := json.Unmarshal(journal.Get(k), &entry); err != nil {
+				return err
+			}
+
+			if entry.From == serial {
+				entries = append(entries, entry)
+				serial = entry.To
+			} else if len(entries) != 0 {
+				entries = nil
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// Get the keys of all journal entries for a zone in order
+func journalKeys(journal *bolt.Bucket, name string) [][]byte {
+	var keys [][]byte
+
+	prefix := []byte(name + "*")
+	c := journal.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, append([]byte{}, k...))
+	}
+
+	return keys
+}
+
+// Remove the journal and snapshot of a zone
+func deleteJournal(tx *bolt.Tx, name string) error {
+	journal := tx.Bucket([]byte("journal"))
+	for _, k := range journalKeys(journal, name) {
+		if err := journal.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return tx.Bucket([]byte("snapshots")).Delete([]byte(name))
+}
+
+func putSnapshot(snapshots *bolt.Bucket, name string, s snapshot) error {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return snapshots.Put([]byte(name), encoded)
+}
+
+// Find the records only in the old set and those only in the new set
+func difference(old, new []string) ([]string, []string) {
+	oldSet := make(map[string]bool)
+	for _, record := range old {
+		oldSet[record] = true
+	}
+	newSet := make(map[string]bool)
+	for _, record := range new {
+		newSet[record] = true
+	}
+
+	deleted := []string{}
+	for _, record := range old {
+		if !newSet[record] {
+			deleted = append(deleted, record)
+		}
+	}
+	added := []string{}
+	for _, record := range new {
+		if !oldSet[record] {
+			added = append(added, record)
+		}
+	}
+
+	return deleted, added
+}
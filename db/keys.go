@@ -0,0 +1,93 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"strconv"
+	"strings"
+)
+
+// A key pair generated by the server to sign a zone with.
+// Times are unix timestamps, with 0 meaning the event is not scheduled.
+type SigningKey struct {
+	Zone       string `json:"zone"`
+	Tag        uint16 `json:"tag"`
+	Type       string `json:"type"`
+	Algorithm  uint8  `json:"algorithm"`
+	Flags      uint16 `json:"flags"`
+	PublicKey  string `json:"public-key"`
+	PrivateKey string `json:"private-key"`
+	Created    int64  `json:"created"`
+	Publish    int64  `json:"publish"`
+	Activate   int64  `json:"activate"`
+	Retire     int64  `json:"retire"`
+	Remove     int64  `json:"remove"`
+}
+
+// Signing keys are stored as zone*tag
+func signingKeyKey(zone string, tag uint16) []byte {
+	return []byte(NormalizeZone(zone) + "*" + strconv.Itoa(int(tag)))
+}
+
+func (k *SigningKey) Encode(db *bolt.DB) error {
+	j, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("keys")).Put(signingKeyKey(k.Zone, k.Tag), j)
+	})
+}
+
+func GetSigningKey(zone string, tag uint16, db *bolt.DB) (*SigningKey, error) {
+	var k *SigningKey
+
+	if err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("keys")).Get(signingKeyKey(zone, tag)); len(value) != 0 {
+			k = &SigningKey{}
+			return json.Unmarshal(value, k)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// List the signing keys of a zone, or of every zone if the name is empty
+func ListSigningKeys(zone string, db *bolt.DB) ([]SigningKey, error) {
+	keys := []SigningKey{}
+	prefix := NormalizeZone(zone) + "*"
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("keys")).ForEach(func(k, v []byte) error {
+			if zone != "" && !strings.HasPrefix(string(k), prefix) {
+				return nil
+			}
+
+			var key SigningKey
+			if err := json.Unmarshal(v, &key); err != nil {
+				return err
+			}
+
+			keys = append(keys, key)
+			return nil
+		})
+	})
+
+	return keys, err
+}
+
+func DeleteSigningKey(zone string, tag uint16, db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket([]byte("keys"))
+
+		if value := keys.Get(signingKeyKey(zone, tag)); len(value) == 0 {
+			return fmt.Errorf("key does not exist")
+		}
+		return keys.Delete(signingKeyKey(zone, tag))
+	})
+}
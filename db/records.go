@@ -9,26 +9,37 @@ type Record interface {
 	Name() string
 }
 
+// All supported record types, each stored in a bucket of the same name
+var RecordTypes = []string{"A", "AAAA", "CNAME", "MX", "LOC", "SRV", "SPF", "TXT", "NS", "CAA", "PTR", "CERT", "DNSKEY", "DS", "NAPTR", "SMIMEA", "SSHFP", "TLSA", "URI"}
+
 // Parts of an A record
 type A struct {
+	ID      string `json:"id"`
+	TTL     uint32 `json:"ttl"`
 	Address net.IP `json:"host"`
 }
 func (a A) Name() string { return "A" }
 
 // Parts of an AAAA record
 type AAAA struct {
+	ID      string `json:"id"`
+	TTL     uint32 `json:"ttl"`
 	Address net.IP `json:"host"`
 }
 func (a AAAA) Name() string { return "AAAA" }
 
 // Parts of a CNAME record
 type CNAME struct {
+	ID     string `json:"id"`
+	TTL    uint32 `json:"ttl"`
 	Target string `json:"target"`
 }
 func (c CNAME) Name() string { return "CNAME" }
 
 // Parts of a MX record
 type MX struct {
+	ID       string `json:"id"`
+	TTL      uint32 `json:"ttl"`
 	Host     string `json:"host"`
 	Priority uint16 `json:"priority"`
 }
@@ -36,6 +47,8 @@ func (m MX) Name() string { return "MX" }
 
 // Parts of a LOC record
 type LOC struct {
+	ID                  string `json:"id"`
+	TTL                 uint32 `json:"ttl"`
 	Version             uint8  `json:"version"`
 	Size                uint8  `json:"size"`
 	HorizontalPrecision uint8  `json:"horizontal-precision"`
@@ -57,6 +70,8 @@ func (l LOC) ToParsable() (string, uint8) {
 
 // Parts of a SRV record
 type SRV struct {
+	ID       string `json:"id"`
+	TTL      uint32 `json:"ttl"`
 	Priority uint16 `json:"priority"`
 	Weight   uint16 `json:"weight"`
 	Port     uint16 `json:"port"`
@@ -66,24 +81,32 @@ func (s SRV) Name() string { return "SRV" }
 
 // Parts of a SPF record
 type SPF struct {
+	ID   string   `json:"id"`
+	TTL  uint32   `json:"ttl"`
 	Text []string `json:"text"`
 }
 func (s SPF) Name() string { return "SPF" }
 
 // Parts of a TXT record
 type TXT struct {
+	ID   string   `json:"id"`
+	TTL  uint32   `json:"ttl"`
 	Text []string `json:"text"`
 }
 func (t TXT) Name() string { return "TXT" }
 
 // Parts of a NS record
 type NS struct {
+	ID         string `json:"id"`
+	TTL        uint32 `json:"ttl"`
 	Nameserver string `json:"nameserver"`
 }
 func (n NS) Name() string { return "NS" }
 
 // Parts of a CAA record
 type CAA struct {
+	ID      string `json:"id"`
+	TTL     uint32 `json:"ttl"`
 	Flag    uint8  `json:"flag"`
 	Tag     string `json:"tag"`
 	Content string `json:"content"`
@@ -92,12 +115,16 @@ func (c CAA) Name() string { return "CAA" }
 
 // Parts of a PTR record
 type PTR struct {
+	ID     string `json:"id"`
+	TTL    uint32 `json:"ttl"`
 	Domain string `json:"domain"`
 }
 func (p PTR) Name() string { return "PTR" }
 
 // Parts of a CERT record
 type CERT struct {
+	ID          string `json:"id"`
+	TTL         uint32 `json:"ttl"`
 	Type        uint16 `json:"c-type"`
 	KeyTag      uint16 `json:"key-tag"`
 	Algorithm   uint8  `json:"algorithm"`
@@ -107,6 +134,8 @@ func (c CERT) Name() string { return "CERT" }
 
 // Parts of a DNSKEY record
 type DNSKEY struct {
+	ID        string `json:"id"`
+	TTL       uint32 `json:"ttl"`
 	Flags     uint16 `json:"flags"`
 	Protocol  uint8  `json:"protocol"`
 	Algorithm uint8  `json:"algorithm"`
@@ -116,6 +145,8 @@ func (d DNSKEY) Name() string { return "DNSKEY" }
 
 // Parts of a DS record
 type DS struct {
+	ID         string `json:"id"`
+	TTL        uint32 `json:"ttl"`
 	KeyTag     uint16 `json:"key-tag"`
 	Algorithm  uint8  `json:"algorithm"`
 	DigestType uint8  `json:"digest-type"`
@@ -125,6 +156,8 @@ func (d DS) Name() string { return "DS" }
 
 // Parts of a NAPTR record
 type NAPTR struct {
+	ID          string `json:"id"`
+	TTL         uint32 `json:"ttl"`
 	Order       uint16 `json:"order"`
 	Preference  uint16 `json:"preference"`
 	Flags       string `json:"flags"`
@@ -136,6 +169,8 @@ func (n NAPTR) Name() string { return "NAPTR" }
 
 // Parts of a SMIMEA record
 type SMIMEA struct {
+	ID           string `json:"id"`
+	TTL          uint32 `json:"ttl"`
 	Usage        uint8  `json:"usage"`
 	Selector     uint8  `json:"selector"`
 	MatchingType uint8  `json:"matching-type"`
@@ -145,6 +180,8 @@ func (s SMIMEA) Name() string { return "SMIMEA" }
 
 // Parts of a SSHFP record
 type SSHFP struct {
+	ID          string `json:"id"`
+	TTL         uint32 `json:"ttl"`
 	Algorithm   uint8  `json:"algorithm"`
 	Type        uint8  `json:"s-type"`
 	Fingerprint string `json:"fingerprint"`
@@ -153,6 +190,8 @@ func (s SSHFP) Name() string { return "SSHFP" }
 
 // Parts of a TLSA record
 type TLSA struct {
+	ID           string `json:"id"`
+	TTL          uint32 `json:"ttl"`
 	Usage        uint8  `json:"usage"`
 	Selector     uint8  `json:"selector"`
 	MatchingType uint8  `json:"matching-type"`
@@ -162,6 +201,8 @@ func (t TLSA) Name() string { return "TLSA" }
 
 // Parts of a URI record
 type URI struct {
+	ID       string `json:"id"`
+	TTL      uint32 `json:"ttl"`
 	Priority uint16 `json:"priority"`
 	Weight   uint16 `json:"weight"`
 	Target   string `json:"target"`
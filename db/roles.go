@@ -65,25 +65,30 @@ func DeleteRole(name string, db *bolt.DB) error {
 }
 
 func EvaluateRole(name, record string, db *bolt.DB) (bool, error) {
-	// Allow by default
-	approved := true
-
 	// Retrieve role
 	role, err := GetRole(name, db)
 	if err != nil {
 		return false, err
 	}
 
+	return role.Evaluate(record)
+}
+
+// Check if the rules of a role allow a record name
+func (r *Role) Evaluate(record string) (bool, error) {
+	// Allow by default
+	approved := true
+
 	// Evaluate rules if they exist
-	if role.Deny != "" {
-		matched, err := regexp.Match(role.Deny, []byte(record))
+	if r.Deny != "" {
+		matched, err := regexp.Match(r.Deny, []byte(record))
 		if err != nil {
 			return false, err
 		}
 		approved = !matched
 	}
-	if role.Allow != "" {
-		matched, err := regexp.Match(role.Allow, []byte(record))
+	if r.Allow != "" {
+		matched, err := regexp.Match(r.Allow, []byte(record))
 		if err != nil {
 			return false, err
 		}
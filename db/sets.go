@@ -0,0 +1,212 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Members of a record set are stored as one key per field in the
+// format name*id*field, where id is unique to the member within its type
+
+// Build the key of a single field of a record set member
+func key(name, id, field string) []byte {
+	return []byte(name + "*" + id + "*" + field)
+}
+
+// Split a key into the name, member id, and field it is made of
+func SplitKey(k string) (string, string, string) {
+	fieldSep := strings.LastIndex(k, "*")
+	if fieldSep == -1 {
+		return k, "", ""
+	}
+	idSep := strings.LastIndex(k[:fieldSep], "*")
+	if idSep == -1 {
+		return k[:fieldSep], "", k[fieldSep+1:]
+	}
+	return k[:idSep], k[idSep+1 : fieldSep], k[fieldSep+1:]
+}
+
+// Retrieve the fields of all members of a record set, with the ids sorted by age
+func members(records *bolt.Bucket, name string) ([]string, map[string]map[string][]byte) {
+	var ids []string
+	fields := make(map[string]map[string][]byte)
+
+	prefix := []byte(name + "*")
+	c := records.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		n, id, field := SplitKey(string(k))
+		if n != name || id == "" {
+			continue
+		}
+
+		if _, ok := fields[id]; !ok {
+			ids = append(ids, id)
+			fields[id] = make(map[string][]byte)
+		}
+		fields[id][field] = v
+	}
+
+	// Keys are sorted as strings, ids are sequential numbers
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseUint(ids[i], 10, 64)
+		b, _ := strconv.ParseUint(ids[j], 10, 64)
+		return a < b
+	})
+
+	return ids, fields
+}
+
+// Check if a record set has any members
+func exists(records *bolt.Bucket, name string) bool {
+	prefix := []byte(name + "*")
+	c := records.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		if n, id, _ := SplitKey(string(k)); n == name && id != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Names with records are also kept in an index of their labels in reverse, as in
+// com.example.www, so the descendants of a name directly follow it
+
+// Build the index key of a name
+func indexKey(name string) []byte {
+	labels := strings.Split(name, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return []byte(strings.Join(labels, "."))
+}
+
+// Add a name to the index of a view
+func indexName(tx *bolt.Tx, view, name string) error {
+	return Bucket(tx, view, "names").Put(indexKey(name), []byte{1})
+}
+
+// Index all names with records in a view
+func indexNames(tx *bolt.Tx, view string) error {
+	for _, recordType := range RecordTypes {
+		if err := Bucket(tx, view, recordType).ForEach(func(k, v []byte) error {
+			if n, id, _ := SplitKey(string(k)); id != "" {
+				return indexName(tx, view, n)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check if a name exists, either with records of its own or as an ancestor of another name
+func nameExists(tx *bolt.Tx, view, name string) bool {
+	c := Bucket(tx, view, "names").Cursor()
+	k := indexKey(name)
+	if found, _ := c.Seek(k); bytes.Equal(found, k) {
+		return true
+	}
+
+	prefix := append(k, '.')
+	found, _ := c.Seek(prefix)
+	return found != nil && bytes.HasPrefix(found, prefix)
+}
+
+// Get the id to write a member to, generating a new one if none is given
+func member(records *bolt.Bucket, id string) (string, error) {
+	if id != "" {
+		return id, nil
+	}
+
+	seq, err := records.NextSequence()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(seq, 10), nil
+}
+
+// Returned when a member is written with the same data as another member of its set
+var ErrRecordExists = fmt.Errorf("record already exists")
+
+// Check that no other member of a set has the same data as the one just written, ignoring
+// the TTL. A duplicate member is removed, and the id of the existing one is returned.
+func unique(records *bolt.Bucket, name, id string) (string, error) {
+	ids, fields := members(records, name)
+	for _, other := range ids {
+		if other == id || len(fields[other]) != len(fields[id]) {
+			continue
+		}
+
+		same := true
+		for field, value := range fields[id] {
+			if existing, ok := fields[other][field]; field != "ttl" && (!ok || !bytes.Equal(existing, value)) {
+				same = false
+				break
+			}
+		}
+		if same {
+			if err := deleteMembers(records, name, id); err != nil {
+				return id, err
+			}
+			return other, ErrRecordExists
+		}
+	}
+	return id, nil
+}
+
+// Read the TTL of a member, where 0 means the default should be used
+func readTTL(fields map[string][]byte) uint32 {
+	if value := fields["ttl"]; len(value) >= 4 {
+		return binary.BigEndian.Uint32(value)
+	}
+	return 0
+}
+
+// Write the TTL of a member
+func writeTTL(records *bolt.Bucket, name, id string, ttl uint32) error {
+	t := make([]byte, 4)
+	binary.BigEndian.PutUint32(t, ttl)
+	return records.Put(key(name, id, "ttl"), t)
+}
+
+// Delete a single member of a record set, or the entire set if no id is given
+func deleteMembers(records *bolt.Bucket, name, id string) error {
+	var keys [][]byte
+
+	prefix := []byte(name + "*")
+	c := records.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		if n, i, _ := SplitKey(string(k)); n == name && (id == "" || i == id) {
+			keys = append(keys, append([]byte{}, k...))
+		}
+	}
+
+	// Keys cannot be removed while iterating
+	for _, k := range keys {
+		if err := records.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete members of a record set, removing the name from the index once it has no records left
+func removeMembers(tx *bolt.Tx, view, recordType, name, id string) error {
+	if err := deleteMembers(Bucket(tx, view, recordType), name, id); err != nil {
+		return err
+	}
+
+	for _, t := range RecordTypes {
+		if exists(Bucket(tx, view, t), name) {
+			return nil
+		}
+	}
+	return Bucket(tx, view, "names").Delete(indexKey(name))
+}
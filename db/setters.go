@@ -6,129 +6,250 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
-func (s set) A(name, host string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("A")).Put([]byte(name), []byte(host))
+func (s set) A(name, id string, ttl uint32, host string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "A")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
+
+		if err := records.Put(key(name, id, "host"), []byte(host)); err != nil {
+			return err
+		}
+
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) AAAA(name, host string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("AAAA")).Put([]byte(name), []byte(host))
+func (s set) AAAA(name, id string, ttl uint32, host string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "AAAA")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
+
+		if err := records.Put(key(name, id, "host"), []byte(host)); err != nil {
+			return err
+		}
+
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set)CNAME(name, target string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("CNAME")).Put([]byte(name), []byte(target))
+func (s set) CNAME(name, id string, ttl uint32, target string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "CNAME")
+
+		// Only a single CNAME can exist for a name, so replace any others
+		if err := deleteMembers(records, name, ""); err != nil {
+			return err
+		}
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
+
+		return records.Put(key(name, id, "target"), []byte(target))
 	})
+	return id, err
 }
 
-func (s set) MX(name string, priority uint16, host string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("MX"))
+func (s set) MX(name, id string, ttl uint32, priority uint16, host string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "MX")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Convert uint16 to binary
-		p := make([]byte, binary.MaxVarintLen16)
+		p := make([]byte, 2)
 		binary.BigEndian.PutUint16(p, priority)
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*priority"), p); err != nil {
+		if err := records.Put(key(name, id, "priority"), p); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*host"), []byte(host)); err != nil {
+		if err := records.Put(key(name, id, "host"), []byte(host)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) LOC(name string, version, size, horizontal, vertical uint8, altitude uint32, latDegrees, latMinutes, latSeconds uint8, latDirection string, longDegrees, longMinutes, longSeconds uint8, longDirection string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("LOC"))
+func (s set) LOC(name, id string, ttl uint32, version, size, horizontal, vertical uint8, altitude uint32, latDegrees, latMinutes, latSeconds uint8, latDirection string, longDegrees, longMinutes, longSeconds uint8, longDirection string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "LOC")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Convert uint32s to binary
-		alt := make([]byte, binary.MaxVarintLen32)
+		alt := make([]byte, 4)
 		binary.BigEndian.PutUint32(alt, altitude)
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*version"), []byte{version}); err != nil {
+		if err := records.Put(key(name, id, "version"), []byte{version}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*size"), []byte{size}); err != nil {
+		if err := records.Put(key(name, id, "size"), []byte{size}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*horiz"), []byte{horizontal}); err != nil {
+		if err := records.Put(key(name, id, "horiz"), []byte{horizontal}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*vert"), []byte{vertical}); err != nil {
+		if err := records.Put(key(name, id, "vert"), []byte{vertical}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*alt"), alt); err != nil {
+		if err := records.Put(key(name, id, "alt"), alt); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*lat-degrees"), []byte{latDegrees}); err != nil {
+		if err := records.Put(key(name, id, "lat-degrees"), []byte{latDegrees}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*lat-minutes"), []byte{latMinutes}); err != nil {
+		if err := records.Put(key(name, id, "lat-minutes"), []byte{latMinutes}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*lat-seconds"), []byte{latSeconds}); err != nil {
+		if err := records.Put(key(name, id, "lat-seconds"), []byte{latSeconds}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*lat-direction"), []byte(latDirection)); err != nil {
+		if err := records.Put(key(name, id, "lat-direction"), []byte(latDirection)); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*long-degrees"), []byte{longDegrees}); err != nil {
+		if err := records.Put(key(name, id, "long-degrees"), []byte{longDegrees}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*long-minutes"), []byte{longMinutes}); err != nil {
+		if err := records.Put(key(name, id, "long-minutes"), []byte{longMinutes}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*long-seconds"), []byte{longSeconds}); err != nil {
+		if err := records.Put(key(name, id, "long-seconds"), []byte{longSeconds}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*long-direction"), []byte(longDirection)); err != nil {
+		if err := records.Put(key(name, id, "long-direction"), []byte(longDirection)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) SRV(name string, priority, weight, port uint16, target string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SRV"))
+func (s set) SRV(name, id string, ttl uint32, priority, weight, port uint16, target string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "SRV")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Convert uint16s to binary
-		pri := make([]byte, binary.MaxVarintLen16)
+		pri := make([]byte, 2)
 		binary.BigEndian.PutUint16(pri, priority)
-		wei := make([]byte, binary.MaxVarintLen16)
+		wei := make([]byte, 2)
 		binary.BigEndian.PutUint16(wei, weight)
-		por := make([]byte, binary.MaxVarintLen16)
+		por := make([]byte, 2)
 		binary.BigEndian.PutUint16(por, port)
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*priority"), pri); err != nil {
+		if err := records.Put(key(name, id, "priority"), pri); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*weight"), wei); err != nil {
+		if err := records.Put(key(name, id, "weight"), wei); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*port"), por); err != nil {
+		if err := records.Put(key(name, id, "port"), por); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*target"), []byte(target)); err != nil {
+		if err := records.Put(key(name, id, "target"), []byte(target)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) SPF(name string, text []string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+func (s set) SPF(name, id string, ttl uint32, text []string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "SPF")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
+
 		// Encode to JSON
 		arr, err := json.Marshal(text)
 		if err != nil {
@@ -136,12 +257,33 @@ func (s set) SPF(name string, text []string) error {
 		}
 
 		// Write to bucket
-		return tx.Bucket([]byte("SPF")).Put([]byte(name), arr)
+		if err := records.Put(key(name, id, "text"), arr); err != nil {
+			return err
+		}
+
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) TXT(name string, text []string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+func (s set) TXT(name, id string, ttl uint32, text []string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "TXT")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
+
 		// Encode to JSON
 		arr, err := json.Marshal(text)
 		if err != nil {
@@ -149,235 +291,419 @@ func (s set) TXT(name string, text []string) error {
 		}
 
 		// Write to bucket
-		return tx.Bucket([]byte("TXT")).Put([]byte(name), arr)
+		if err := records.Put(key(name, id, "text"), arr); err != nil {
+			return err
+		}
+
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) NS(name, nameserver string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("NS")).Put([]byte(name), []byte(nameserver))
+func (s set) NS(name, id string, ttl uint32, nameserver string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "NS")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
+
+		if err := records.Put(key(name, id, "nameserver"), []byte(nameserver)); err != nil {
+			return err
+		}
+
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) CAA(name, tag, content string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("CAA"))
+func (s set) CAA(name, id string, ttl uint32, tag, content string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "CAA")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
-		if err := records.Put([]byte(name + "*tag"), []byte(tag)); err != nil {
+		if err := records.Put(key(name, id, "tag"), []byte(tag)); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*content"), []byte(content)); err != nil {
+		if err := records.Put(key(name, id, "content"), []byte(content)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) PTR(name, domain string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("PTR")).Put([]byte(name), []byte(domain))
+func (s set) PTR(name, id string, ttl uint32, domain string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "PTR")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
+
+		if err := records.Put(key(name, id, "domain"), []byte(domain)); err != nil {
+			return err
+		}
+
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) CERT(name string, tpe, keytag uint16, algorithm uint8, certificate string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("CERT"))
+func (s set) CERT(name, id string, ttl uint32, tpe, keytag uint16, algorithm uint8, certificate string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "CERT")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Convert uint16s to binary
-		ty := make([]byte, binary.MaxVarintLen16)
+		ty := make([]byte, 2)
 		binary.BigEndian.PutUint16(ty, tpe)
-		keta := make([]byte, binary.MaxVarintLen16)
+		keta := make([]byte, 2)
 		binary.BigEndian.PutUint16(keta, keytag)
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*type"), ty); err != nil {
+		if err := records.Put(key(name, id, "type"), ty); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*keytag"), keta); err != nil {
+		if err := records.Put(key(name, id, "keytag"), keta); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*algorithm"), []byte{algorithm}); err != nil {
+		if err := records.Put(key(name, id, "algorithm"), []byte{algorithm}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*certificate"), []byte(certificate)); err != nil {
+		if err := records.Put(key(name, id, "certificate"), []byte(certificate)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) DNSKEY(name string, flags uint16, protocol, algorithm uint8, publickey string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("DNSKEY"))
+func (s set) DNSKEY(name, id string, ttl uint32, flags uint16, protocol, algorithm uint8, publickey string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "DNSKEY")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Convert uint16 to binary
-		flgs := make([]byte, binary.MaxVarintLen16)
+		flgs := make([]byte, 2)
 		binary.BigEndian.PutUint16(flgs, flags)
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*flags"), flgs); err != nil {
+		if err := records.Put(key(name, id, "flags"), flgs); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*protocol"), []byte{protocol}); err != nil {
+		if err := records.Put(key(name, id, "protocol"), []byte{protocol}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*algorithm"), []byte{algorithm}); err != nil {
+		if err := records.Put(key(name, id, "algorithm"), []byte{algorithm}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*publickey"), []byte(publickey)); err != nil {
+		if err := records.Put(key(name, id, "publickey"), []byte(publickey)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) DS(name string, keytag uint16, algorithm, digesttype uint8, digest string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("DS"))
+func (s set) DS(name, id string, ttl uint32, keytag uint16, algorithm, digesttype uint8, digest string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "DS")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Convert uint16 to binary
-		keta := make([]byte, binary.MaxVarintLen16)
+		keta := make([]byte, 2)
 		binary.BigEndian.PutUint16(keta, keytag)
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*keytag"), keta); err != nil {
+		if err := records.Put(key(name, id, "keytag"), keta); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*algorithm"), []byte{algorithm}); err != nil {
+		if err := records.Put(key(name, id, "algorithm"), []byte{algorithm}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*digesttype"), []byte{digesttype}); err != nil {
+		if err := records.Put(key(name, id, "digesttype"), []byte{digesttype}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*digest"), []byte(digest)); err != nil {
+		if err := records.Put(key(name, id, "digest"), []byte(digest)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) NAPTR(name string, order, preference uint16, flags, service, regexp, replacement string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("NAPTR"))
+func (s set) NAPTR(name, id string, ttl uint32, order, preference uint16, flags, service, regexp, replacement string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "NAPTR")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Convert uint16s to binary
-		ordr := make([]byte, binary.MaxVarintLen16)
+		ordr := make([]byte, 2)
 		binary.BigEndian.PutUint16(ordr, order)
-		pref := make([]byte, binary.MaxVarintLen16)
+		pref := make([]byte, 2)
 		binary.BigEndian.PutUint16(pref, preference)
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*order"), ordr); err != nil {
+		if err := records.Put(key(name, id, "order"), ordr); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*preference"), pref); err != nil {
+		if err := records.Put(key(name, id, "preference"), pref); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*flags"), []byte(flags)); err != nil {
+		if err := records.Put(key(name, id, "flags"), []byte(flags)); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*service"), []byte(service)); err != nil {
+		if err := records.Put(key(name, id, "service"), []byte(service)); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*regexp"), []byte(regexp)); err != nil {
+		if err := records.Put(key(name, id, "regexp"), []byte(regexp)); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*replacement"), []byte(replacement)); err != nil {
+		if err := records.Put(key(name, id, "replacement"), []byte(replacement)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) SMIMEA(name string, usage, selector, matchingtype uint8, certificate string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SMIMEA"))
+func (s set) SMIMEA(name, id string, ttl uint32, usage, selector, matchingtype uint8, certificate string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "SMIMEA")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*usage"), []byte{usage}); err != nil {
+		if err := records.Put(key(name, id, "usage"), []byte{usage}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*selector"), []byte{selector}); err != nil {
+		if err := records.Put(key(name, id, "selector"), []byte{selector}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*matching"), []byte{matchingtype}); err != nil {
+		if err := records.Put(key(name, id, "matching"), []byte{matchingtype}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*certificate"), []byte(certificate)); err != nil {
+		if err := records.Put(key(name, id, "certificate"), []byte(certificate)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) SSHFP(name string, algorithm, tpe uint8, fingerprint string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("SSHFP"))
+func (s set) SSHFP(name, id string, ttl uint32, algorithm, tpe uint8, fingerprint string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "SSHFP")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*algorithm"), []byte{algorithm}); err != nil {
+		if err := records.Put(key(name, id, "algorithm"), []byte{algorithm}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*type"), []byte{tpe}); err != nil {
+		if err := records.Put(key(name, id, "type"), []byte{tpe}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*fingerprint"), []byte(fingerprint)); err != nil {
+		if err := records.Put(key(name, id, "fingerprint"), []byte(fingerprint)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) TLSA(name string, usage, selector, matchingtype uint8, certificate string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("TLSA"))
+func (s set) TLSA(name, id string, ttl uint32, usage, selector, matchingtype uint8, certificate string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "TLSA")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*usage"), []byte{usage}); err != nil {
+		if err := records.Put(key(name, id, "usage"), []byte{usage}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*selector"), []byte{selector}); err != nil {
+		if err := records.Put(key(name, id, "selector"), []byte{selector}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*matching"), []byte{matchingtype}); err != nil {
+		if err := records.Put(key(name, id, "matching"), []byte{matchingtype}); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*certificate"), []byte(certificate)); err != nil {
+		if err := records.Put(key(name, id, "certificate"), []byte(certificate)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
 
-func (s set) URI(name string, priority, weight uint16, target string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		records := tx.Bucket([]byte("URI"))
+func (s set) URI(name, id string, ttl uint32, priority, weight uint16, target string) (string, error) {
+	err := s.write(func(tx *bolt.Tx) error {
+		records := Bucket(tx, s.view, "URI")
+
+		// Get the member of the set to write to
+		var err error
+		if id, err = member(records, id); err != nil {
+			return err
+		}
+		if err := indexName(tx, s.view, name); err != nil {
+			return err
+		}
+		if err := writeTTL(records, name, id, ttl); err != nil {
+			return err
+		}
 
 		// Convert uint16s to binary
-		pri := make([]byte, binary.MaxVarintLen16)
+		pri := make([]byte, 2)
 		binary.BigEndian.PutUint16(pri, priority)
-		wei := make([]byte, binary.MaxVarintLen16)
+		wei := make([]byte, 2)
 		binary.BigEndian.PutUint16(wei, weight)
 
 		// Write data to bucket
-		if err := records.Put([]byte(name + "*priority"), pri); err != nil {
+		if err := records.Put(key(name, id, "priority"), pri); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*weight"), wei); err != nil {
+		if err := records.Put(key(name, id, "weight"), wei); err != nil {
 			return err
 		}
-		if err := records.Put([]byte(name + "*target"), []byte(target)); err != nil {
+		if err := records.Put(key(name, id, "target"), []byte(target)); err != nil {
 			return err
 		}
 
-		return nil
+		// A set cannot hold the same data twice
+		id, err = unique(records, name, id)
+		return err
 	})
+	return id, err
 }
@@ -5,8 +5,20 @@ import (
 	bolt "go.etcd.io/bbolt"
 	"gopkg.in/hlandau/passlib.v1"
 	"log"
+	"strings"
 )
 
+// Field names for record types that were stored directly under their name
+var singleValueFields = map[string]string{
+	"A":     "host",
+	"AAAA":  "host",
+	"CNAME": "target",
+	"SPF":   "text",
+	"TXT":   "text",
+	"NS":    "nameserver",
+	"PTR":   "domain",
+}
+
 func Setup(db *bolt.DB) error {
 	// Create buckets for data
 	if err := db.Update(func(tx *bolt.Tx) error {
@@ -31,10 +43,32 @@ func Setup(db *bolt.DB) error {
 		if _, err := tx.CreateBucketIfNotExists([]byte("TLSA")); err != nil { return err }
 		if _, err := tx.CreateBucketIfNotExists([]byte("URI")); err != nil { return err }
 
+		// Convert records from before record sets were supported
+		if err := migrateRecordSets(tx); err != nil { return err }
+
+		// Index the names with records when upgrading from before the index existed
+		if tx.Bucket([]byte("names")) == nil {
+			if _, err := tx.CreateBucket([]byte("names")); err != nil { return err }
+			if err := indexNames(tx, ""); err != nil { return err }
+		}
+
 		// Setup authentication
 		if _, err := tx.CreateBucketIfNotExists([]byte("users")); err != nil { return err }
 		if _, err := tx.CreateBucketIfNotExists([]byte("tokens")); err != nil { return err }
 		if _, err := tx.CreateBucketIfNotExists([]byte("roles")); err != nil { return err }
+
+		// Setup zones
+		if _, err := tx.CreateBucketIfNotExists([]byte("zones")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("journal")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("snapshots")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("keys")); err != nil { return err }
+
+		// Setup blocklists
+		if _, err := tx.CreateBucketIfNotExists([]byte("blocklists")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("exceptions")); err != nil { return err }
+
+		// Setup forwarding rules
+		if _, err := tx.CreateBucketIfNotExists([]byte("forwarders")); err != nil { return err }
 		return nil
 	}); err != nil {
 		return err
@@ -55,3 +89,48 @@ func Setup(db *bolt.DB) error {
 
 	return nil
 }
+
+// Move records stored as name or name*field into the first member of a record set
+func migrateRecordSets(tx *bolt.Tx) error {
+	for _, recordType := range RecordTypes {
+		records := tx.Bucket([]byte(recordType))
+
+		// Find all keys without a member id
+		old := make(map[string][]byte)
+		if err := records.ForEach(func(k, v []byte) error {
+			if strings.Count(string(k), "*") < 2 {
+				old[string(k)] = append([]byte{}, v...)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// Rewrite each name as a single member
+		ids := make(map[string]string)
+		for k, v := range old {
+			parts := strings.SplitN(k, "*", 2)
+			name, field := parts[0], singleValueFields[recordType]
+			if len(parts) == 2 {
+				field = parts[1]
+			}
+
+			if _, ok := ids[name]; !ok {
+				id, err := member(records, "")
+				if err != nil {
+					return err
+				}
+				ids[name] = id
+			}
+
+			if err := records.Put(key(name, ids[name], field), v); err != nil {
+				return err
+			}
+			if err := records.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
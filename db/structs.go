@@ -13,15 +13,81 @@ var (
 
 // Getters for different record types
 type get struct {
-	Db *bolt.DB
+	Db   *bolt.DB
+	view string
+	tx   *bolt.Tx
 }
 
 // Setters for different record types
 type set struct {
-	Db *bolt.DB
+	Db   *bolt.DB
+	view string
+	tx   *bolt.Tx
 }
 
 // Delete different record types
 type deleteRecord struct {
-	Db *bolt.DB
+	Db   *bolt.DB
+	view string
+	tx   *bolt.Tx
+}
+
+// Get records from a view instead of the default one
+func (g get) In(view string) get {
+	g.view = view
+	return g
+}
+
+// Set records in a view instead of the default one
+func (s set) In(view string) set {
+	s.view = view
+	return s
+}
+
+// Delete records from a view instead of the default one
+func (d deleteRecord) In(view string) deleteRecord {
+	d.view = view
+	return d
+}
+
+// Get records within a transaction, so changes made in it are seen
+func (g get) Within(tx *bolt.Tx) get {
+	g.tx = tx
+	return g
+}
+
+// Set records within a transaction, so they are only written if all of its changes are
+func (s set) Within(tx *bolt.Tx) set {
+	s.tx = tx
+	return s
+}
+
+// Delete records within a transaction, so they are only removed if all of its changes are
+func (d deleteRecord) Within(tx *bolt.Tx) deleteRecord {
+	d.tx = tx
+	return d
+}
+
+// Read from the transaction if there is one, otherwise in a new one
+func (g get) read(fn func(*bolt.Tx) error) error {
+	if g.tx != nil {
+		return fn(g.tx)
+	}
+	return g.Db.View(fn)
+}
+
+// Write in the transaction if there is one, otherwise in a new one
+func (s set) write(fn func(*bolt.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	return s.Db.Update(fn)
+}
+
+// Write in the transaction if there is one, otherwise in a new one
+func (d deleteRecord) write(fn func(*bolt.Tx) error) error {
+	if d.tx != nil {
+		return fn(d.tx)
+	}
+	return d.Db.Update(fn)
 }
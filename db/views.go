@@ -0,0 +1,58 @@
+package db
+
+import (
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+)
+
+// Records of a view are kept in their own buckets named view/type,
+// while the default view uses the bare record type
+
+// Get the bucket of a record type within a view
+func Bucket(tx *bolt.Tx, view, recordType string) *bolt.Bucket {
+	if view == "" {
+		return tx.Bucket([]byte(recordType))
+	}
+	return tx.Bucket([]byte(view + "/" + recordType))
+}
+
+// Create the buckets to store the records of a view in
+func SetupView(view string, db *bolt.DB) error {
+	if view == "" || strings.Contains(view, "/") {
+		return fmt.Errorf("view names must not be empty or contain '/'")
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, recordType := range RecordTypes {
+			if _, err := tx.CreateBucketIfNotExists([]byte(view + "/" + recordType)); err != nil {
+				return err
+			}
+		}
+
+		// Index the names with records when upgrading from before the index existed
+		if Bucket(tx, view, "names") != nil {
+			return nil
+		}
+		if _, err := tx.CreateBucket([]byte(view + "/names")); err != nil {
+			return err
+		}
+		return indexNames(tx, view)
+	})
+}
+
+// Check if a view has been set up, the default view always exists
+func ViewExists(view string, db *bolt.DB) bool {
+	if view == "" {
+		return true
+	}
+
+	found := false
+	if err := db.View(func(tx *bolt.Tx) error {
+		found = Bucket(tx, view, RecordTypes[0]) != nil
+		return nil
+	}); err != nil {
+		return false
+	}
+	return found
+}
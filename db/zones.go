@@ -0,0 +1,214 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A zone the server is authoritative for along with its SOA fields
+type Zone struct {
+	Name        string `json:"name"`
+	Nameserver  string `json:"nameserver"`
+	Admin       string `json:"admin"`
+	Serial      uint32 `json:"serial"`
+	Refresh     uint32 `json:"refresh"`
+	Retry       uint32 `json:"retry"`
+	Expire      uint32 `json:"expire"`
+	TTL         uint32 `json:"ttl"`
+	NegativeTTL uint32 `json:"negative-ttl"`
+
+	// Addresses, prefixes, and TSIG keys allowed to transfer the zone
+	AllowTransfer []string `json:"allow-transfer"`
+	TransferKeys  []string `json:"transfer-keys"`
+
+	// Primary server of a secondary zone, empty if the zone is managed here
+	Primary string `json:"primary"`
+}
+
+// Check if a zone is transferred from a primary and cannot be changed here
+func (z *Zone) Secondary() bool {
+	return z.Primary != ""
+}
+
+func NewZone(name, nameserver, admin string, refresh, retry, expire, ttl, negativeTTL uint32) Zone {
+	// Serial numbers start in the common YYYYMMDDnn format
+	now := time.Now().UTC()
+	serial := uint32(now.Year()*1000000 + int(now.Month())*10000 + now.Day()*100)
+
+	return Zone{
+		AllowTransfer: []string{},
+		TransferKeys: []string{},
+		Name: NormalizeZone(name),
+		Nameserver: nameserver,
+		Admin: admin,
+		Serial: serial,
+		Refresh: refresh,
+		Retry: retry,
+		Expire: expire,
+		TTL: ttl,
+		NegativeTTL: negativeTTL,
+	}
+}
+
+// Convert a zone name to how it is stored, lowercase without the trailing dot
+func NormalizeZone(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+func GetZone(name string, db *bolt.DB) (*Zone, error) {
+	var z *Zone
+
+	if err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("zones")).Get([]byte(NormalizeZone(name))); len(value) != 0 {
+			z = &Zone{}
+			return json.Unmarshal(value, z)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return z, nil
+}
+
+// Find the closest enclosing zone of a query name, nil if it is not in any zone
+func FindZone(qname string, db *bolt.DB) (*Zone, error) {
+	var z *Zone
+
+	if err := db.View(func(tx *bolt.Tx) error {
+		zones := tx.Bucket([]byte("zones"))
+
+		// Walk up the labels of the name until a zone is found
+		name := NormalizeZone(qname)
+		for name != "" {
+			if value := zones.Get([]byte(name)); len(value) != 0 {
+				z = &Zone{}
+				return json.Unmarshal(value, z)
+			}
+
+			if i := strings.Index(name, "."); i != -1 {
+				name = name[i+1:]
+			} else {
+				name = ""
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return z, nil
+}
+
+func ListZones(db *bolt.DB) ([]Zone, error) {
+	zones := []Zone{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("zones")).ForEach(func(k, v []byte) error {
+			var z Zone
+			if err := json.Unmarshal(v, &z); err != nil {
+				return err
+			}
+
+			zones = append(zones, z)
+			return nil
+		})
+	})
+
+	return zones, err
+}
+
+// Deleting a zone leaves its records in place, they are just no longer served authoritatively
+func DeleteZone(name string, db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		zones := tx.Bucket([]byte("zones"))
+
+		if value := zones.Get([]byte(NormalizeZone(name))); len(value) == 0 {
+			return fmt.Errorf("zone does not exist")
+		}
+
+		// The journal only makes sense for the zone it was built for
+		if err := deleteJournal(tx, NormalizeZone(name)); err != nil {
+			return err
+		}
+		return zones.Delete([]byte(NormalizeZone(name)))
+	})
+}
+
+func (z *Zone) Encode(db *bolt.DB) error {
+	j, err := json.Marshal(z)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("zones")).Put([]byte(z.Name), j)
+	})
+}
+
+// Get all names with records in a zone, excluding those in more specific zones
+func ZoneNames(name string, db *bolt.DB) ([]string, error) {
+	var names []string
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		names, err = zoneNames(name, tx)
+		return err
+	})
+	return names, err
+}
+
+// Get all names with records in a zone within a transaction
+func zoneNames(name string, tx *bolt.Tx) ([]string, error) {
+	name = NormalizeZone(name)
+	var names []string
+
+	zones := tx.Bucket([]byte("zones"))
+	seen := make(map[string]bool)
+
+	for _, recordType := range RecordTypes {
+		if err := tx.Bucket([]byte(recordType)).ForEach(func(k, v []byte) error {
+			n, id, _ := SplitKey(string(k))
+			if id == "" || seen[n] || (n != name && !strings.HasSuffix(n, "."+name)) {
+				return nil
+			}
+			seen[n] = true
+
+			// Names below a child zone belong to that zone
+			for parent := n; parent != name; parent = parent[strings.Index(parent, ".")+1:] {
+				if len(zones.Get([]byte(parent))) != 0 {
+					return nil
+				}
+			}
+
+			names = append(names, n)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete all records in a zone within a transaction, excluding those in more specific zones
+func DeleteZoneRecords(name string, tx *bolt.Tx) error {
+	names, err := zoneNames(name, tx)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range names {
+		for _, recordType := range RecordTypes {
+			if err := removeMembers(tx, "", recordType, n, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
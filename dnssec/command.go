@@ -0,0 +1,82 @@
+package dnssec
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Usage of the key management commands
+const usage = `usage:
+  keys list <zone>                                 list the keys of a zone and their DS records
+  keys generate <zone> <ksk|zsk> [ecdsa|ed25519]   generate a key, pre-publishing ZSKs that replace another
+  keys retire <zone> <tag>                         stop signing with a key and remove it after the delay`
+
+// Run a key management command from the command line
+func (s *Signer) Command(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf(usage)
+	}
+
+	switch args[0] {
+	case "list":
+		now := time.Now()
+		for _, key := range s.AllKeys(args[1]) {
+			fmt.Printf("%d %s %s %s\n", key.DNSKEY.KeyTag(), keyType(key), key.State(now), timing(key))
+			fmt.Println(key.DNSKEY.String())
+			for _, ds := range DS(key.DNSKEY) {
+				fmt.Println(ds.String())
+			}
+			fmt.Println()
+		}
+		return nil
+
+	case "generate":
+		if len(args) < 3 {
+			return fmt.Errorf(usage)
+		}
+		algorithm := "ecdsa"
+		if len(args) > 3 {
+			algorithm = args[3]
+		}
+
+		key, err := s.Generate(args[1], args[2], algorithm)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Generated %s %d for zone '%s', active from %s\n", key.Type, key.Tag, key.Zone, time.Unix(key.Activate, 0).Format(time.RFC3339))
+		return nil
+
+	case "retire":
+		if len(args) < 3 {
+			return fmt.Errorf(usage)
+		}
+		tag, err := strconv.ParseUint(args[2], 10, 16)
+		if err != nil {
+			return fmt.Errorf("key tag must be a number: %v", err)
+		}
+		return s.Retire(args[1], uint16(tag))
+
+	default:
+		return fmt.Errorf(usage)
+	}
+}
+
+// Get the type of a key as shown to users
+func keyType(key *Key) string {
+	if key.KSK() {
+		return "ksk"
+	}
+	return "zsk"
+}
+
+// Describe when a key is published, active, retired, and removed
+func timing(key *Key) string {
+	format := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("publish=%s activate=%s retire=%s remove=%s", format(key.Publish), format(key.Activate), format(key.Retire), format(key.Remove))
+}
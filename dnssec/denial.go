@@ -0,0 +1,169 @@
+package dnssec
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/authority"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Denial of existence is answered with minimally covering NSEC records as described in RFC 4470,
+// so the zone does not need to be enumerated and cannot be walked.
+
+// Build the NSEC records proving a name or type does not exist in a view. The types at
+// name are used for NODATA, where name is the wildcard the answer came from, if any.
+func (s *Signer) Deny(view, qname, name string, zone *db.Zone, nxdomain bool) []dns.RR {
+	qname = strings.ToLower(dns.Fqdn(qname))
+	negativeTTL := authority.SOA(zone).Minttl
+	if soaTTL := authority.TTL(0, zone); soaTTL < negativeTTL {
+		negativeTTL = soaTTL
+	}
+
+	if !nxdomain {
+		// The name exists, so list the types it has
+		return []dns.RR{s.nsec(qname, "\\000."+qname, s.types(view, name, zone), negativeTTL)}
+	}
+
+	// Cover the name itself and the wildcard that could have matched it
+	records := []dns.RR{s.nsec(predecessor(qname), "\\000."+qname, nil, negativeTTL)}
+
+	encloser := closestEncloser(view, qname, zone)
+	wildcard := "*." + encloser
+	if encloser == "." {
+		wildcard = "*."
+	}
+	if wildcard != qname {
+		records = append(records, s.nsec(predecessor(wildcard), "\\000."+wildcard, nil, negativeTTL))
+	}
+
+	return records
+}
+
+// Build an NSEC record, which always lists itself and its signature
+func (s *Signer) nsec(owner, next string, types []uint16, ttl uint32) *dns.NSEC {
+	types = append(types, dns.TypeNSEC, dns.TypeRRSIG)
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: owner, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: ttl},
+		NextDomain: next,
+		TypeBitMap: types,
+	}
+}
+
+// Get the types that exist at a name
+func (s *Signer) types(view, name string, zone *db.Zone) []uint16 {
+	var types []uint16
+	for _, recordType := range db.RecordTypes {
+		rrtype := dns.StringToType[recordType]
+		if len(authority.LookupView(view, name, name, rrtype, dns.ClassINET, zone)) != 0 {
+			types = append(types, rrtype)
+		}
+	}
+
+	// The apex also has the SOA and the keys of the zone
+	if strings.ToLower(name) == dns.Fqdn(zone.Name) {
+		types = append(types, dns.TypeSOA)
+		if s.Signed(zone.Name) && len(authority.LookupView(view, name, name, dns.TypeDNSKEY, dns.ClassINET, zone)) == 0 {
+			types = append(types, dns.TypeDNSKEY)
+		}
+	}
+
+	return types
+}
+
+// Find the closest ancestor of a name that exists, at most the zone apex
+func closestEncloser(view, qname string, zone *db.Zone) string {
+	apex := dns.Fqdn(zone.Name)
+	for name := qname; name != apex; {
+		labels := dns.SplitDomainName(name)
+		if len(labels) <= 1 {
+			return apex
+		}
+
+		name = dns.Fqdn(strings.Join(labels[1:], "."))
+		if name == apex || db.Get.In(view).NameExists(name) {
+			return name
+		}
+	}
+	return apex
+}
+
+// Get a name that sorts just before another in canonical order, as described in RFC 4471.
+// The last octet of the first label is decremented and padded with the highest octet.
+func predecessor(name string) string {
+	labels := dns.SplitDomainName(name)
+	if len(labels) == 0 {
+		return "."
+	}
+
+	first := unescape(labels[0])
+	parent := "."
+	if len(labels) > 1 {
+		parent = dns.Fqdn(strings.Join(labels[1:], "."))
+	}
+
+	last := first[len(first)-1]
+	if last == 0 {
+		// Dropping a trailing zero octet gives the closest shorter label
+		first = first[:len(first)-1]
+		if len(first) == 0 {
+			return parent
+		}
+	} else {
+		last--
+		// Uppercase letters sort as lowercase, so they cannot be used
+		if last >= 'A' && last <= 'Z' {
+			last = 'A' - 1
+		}
+		first[len(first)-1] = last
+
+		// Pad with the highest octet up to the label and name length limits
+		room := 255 - len(unescape(parent)) - 2 - len(first)
+		if parent == "." {
+			room = 255 - 2 - len(first)
+		}
+		for i := 0; i < room && len(first) < 63; i++ {
+			first = append(first, 0xff)
+		}
+	}
+
+	return escape(first) + "." + strings.TrimPrefix(parent, ".")
+}
+
+// Convert a label in presentation format to its octets
+func unescape(label string) []byte {
+	var octets []byte
+	for i := 0; i < len(label); i++ {
+		if label[i] != '\\' || i+1 >= len(label) {
+			octets = append(octets, label[i])
+		} else if i+3 < len(label) && isDigit(label[i+1]) && isDigit(label[i+2]) && isDigit(label[i+3]) {
+			value, _ := strconv.Atoi(label[i+1 : i+4])
+			octets = append(octets, byte(value))
+			i += 3
+		} else {
+			octets = append(octets, label[i+1])
+			i++
+		}
+	}
+	return octets
+}
+
+// Convert label octets to presentation format
+func escape(octets []byte) string {
+	var label strings.Builder
+	for _, octet := range octets {
+		if (octet >= 'a' && octet <= 'z') || isDigit(octet) || octet == '-' || octet == '_' || octet == '*' {
+			label.WriteByte(octet)
+		} else {
+			label.WriteString("\\" + strconv.Itoa(int(octet)/100) + strconv.Itoa(int(octet)/10%10) + strconv.Itoa(int(octet)%10))
+		}
+	}
+	return label.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,106 @@
+package dnssec
+
+import (
+	"crypto"
+	"fmt"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	"os"
+	"time"
+)
+
+// A key that signs records for a zone.
+// Keys loaded from files have no timing and are always published and active.
+type Key struct {
+	Zone    string
+	DNSKEY  *dns.DNSKEY
+	Private crypto.Signer
+
+	Publish  time.Time
+	Activate time.Time
+	Retire   time.Time
+	Remove   time.Time
+}
+
+// A key pair in the format written by dnssec-keygen, configured for a zone
+type KeyFile struct {
+	Zone string `mapstructure:"zone"`
+	File string `mapstructure:"file"`
+}
+
+// Check if a key signs the DNSKEY set rather than the rest of the zone
+func (k *Key) KSK() bool {
+	return k.DNSKEY.Flags&dns.SEP != 0
+}
+
+// Check if a key's DNSKEY record should be served
+func (k *Key) Published(now time.Time) bool {
+	return (k.Publish.IsZero() || !now.Before(k.Publish)) && (k.Remove.IsZero() || now.Before(k.Remove))
+}
+
+// Check if a key should be used to sign records
+func (k *Key) Active(now time.Time) bool {
+	return (k.Activate.IsZero() || !now.Before(k.Activate)) && (k.Retire.IsZero() || now.Before(k.Retire))
+}
+
+// Get where a key is in its lifecycle
+func (k *Key) State(now time.Time) string {
+	if !k.Published(now) && now.Before(k.Publish) {
+		return "scheduled"
+	} else if !k.Published(now) {
+		return "removed"
+	} else if k.Active(now) {
+		return "active"
+	} else if now.Before(k.Activate) {
+		return "published"
+	}
+	return "retired"
+}
+
+// Get the DS records to give to the parent zone for a key
+func DS(dnskey *dns.DNSKEY) []*dns.DS {
+	var records []*dns.DS
+	for _, digest := range []uint8{dns.SHA256, dns.SHA384} {
+		if ds := dnskey.ToDS(digest); ds != nil {
+			records = append(records, ds)
+		}
+	}
+	return records
+}
+
+// Load a key pair from the .key and .private files that share a base name
+func LoadKeyFile(zone, base string) (*Key, error) {
+	public, err := os.Open(base + ".key")
+	if err != nil {
+		return nil, err
+	}
+	defer public.Close()
+
+	rr, err := dns.ReadRR(public, base+".key")
+	if err != nil {
+		return nil, err
+	}
+	dnskey, ok := rr.(*dns.DNSKEY)
+	if !ok {
+		return nil, fmt.Errorf("'%s.key' does not contain a DNSKEY record", base)
+	} else if db.NormalizeZone(dnskey.Hdr.Name) != db.NormalizeZone(zone) {
+		return nil, fmt.Errorf("'%s.key' is for zone '%s' not '%s'", base, dnskey.Hdr.Name, zone)
+	}
+
+	private, err := os.Open(base + ".private")
+	if err != nil {
+		return nil, err
+	}
+	defer private.Close()
+
+	key, err := dnskey.ReadPrivateKey(private, base+".private")
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("'%s.private' does not contain a usable private key", base)
+	}
+
+	return &Key{Zone: db.NormalizeZone(zone), DNSKEY: dnskey, Private: signer}, nil
+}
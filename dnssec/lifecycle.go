@@ -0,0 +1,290 @@
+package dnssec
+
+import (
+	"crypto"
+	"fmt"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	"log"
+	"time"
+)
+
+// How often key timings are checked for rollovers and removals
+const maintenanceInterval = 10 * time.Minute
+
+// Algorithms keys can be generated with
+var Algorithms = map[string]uint8{
+	"ecdsa":   dns.ECDSAP256SHA256,
+	"ed25519": dns.ED25519,
+}
+
+// Load the keys stored in the database, replacing any loaded before
+func (s *Signer) Load() error {
+	stored, err := db.ListSigningKeys("", s.db)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string][]*Key)
+	for _, k := range stored {
+		key, err := s.decode(k)
+		if err != nil {
+			log.Printf("Failed to load key %d for zone '%s': %v", k.Tag, k.Zone, err)
+			continue
+		}
+		keys[key.Zone] = append(keys[key.Zone], key)
+	}
+
+	s.mu.Lock()
+	s.stored = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// Generate a key for a zone and store it. If the zone already has an active ZSK the new one is
+// pre-published and replaces it once the delay has passed. A new KSK is active immediately
+// alongside the old one, which must be retired by hand once the parent has the new DS record.
+func (s *Signer) Generate(zone, keyType, algorithm string) (*db.SigningKey, error) {
+	alg, ok := Algorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("algorithm must be one of 'ecdsa' or 'ed25519'")
+	} else if keyType != "ksk" && keyType != "zsk" {
+		return nil, fmt.Errorf("key type must be one of 'ksk' or 'zsk'")
+	}
+
+	if z, err := db.GetZone(zone, s.db); err != nil {
+		return nil, err
+	} else if z == nil {
+		return nil, fmt.Errorf("zone does not exist")
+	}
+
+	// Generate until the key tag is not used by another key of the zone
+	dnskey := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: dns.Fqdn(db.NormalizeZone(zone)), Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET},
+		Flags:     dns.ZONE,
+		Protocol:  3,
+		Algorithm: alg,
+	}
+	if keyType == "ksk" {
+		dnskey.Flags |= dns.SEP
+	}
+
+	var private crypto.PrivateKey
+	for {
+		var err error
+		if private, err = dnskey.Generate(256); err != nil {
+			return nil, err
+		}
+
+		if existing, err := db.GetSigningKey(zone, dnskey.KeyTag(), s.db); err != nil {
+			return nil, err
+		} else if existing == nil {
+			break
+		}
+	}
+
+	encrypted, err := encrypt(dnskey.PrivateKeyString(private), s.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	key := &db.SigningKey{
+		Zone:       db.NormalizeZone(zone),
+		Tag:        dnskey.KeyTag(),
+		Type:       keyType,
+		Algorithm:  alg,
+		Flags:      dnskey.Flags,
+		PublicKey:  dnskey.PublicKey,
+		PrivateKey: encrypted,
+		Created:    now.Unix(),
+		Publish:    now.Unix(),
+		Activate:   now.Unix(),
+	}
+
+	// Resolvers must see the new ZSK before anything is signed with it
+	if keyType == "zsk" {
+		current := s.current(zone, false, now)
+		if len(current) != 0 {
+			key.Activate = now.Add(s.delay).Unix()
+		}
+
+		for _, k := range current {
+			stored, err := db.GetSigningKey(zone, k.DNSKEY.KeyTag(), s.db)
+			if err != nil {
+				return nil, err
+			} else if stored == nil {
+				continue
+			}
+
+			stored.Retire = key.Activate
+			stored.Remove = key.Activate + int64(s.delay/time.Second)
+			if err := stored.Encode(s.db); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := key.Encode(s.db); err != nil {
+		return nil, err
+	}
+	return key, s.Load()
+}
+
+// Stop signing with a key, removing it once the delay has passed
+func (s *Signer) Retire(zone string, tag uint16) error {
+	key, err := db.GetSigningKey(zone, tag, s.db)
+	if err != nil {
+		return err
+	} else if key == nil {
+		return fmt.Errorf("key does not exist")
+	}
+
+	now := time.Now()
+	if key.Retire == 0 || key.Retire > now.Unix() {
+		key.Retire = now.Unix()
+	}
+	key.Remove = key.Retire + int64(s.delay/time.Second)
+
+	if err := key.Encode(s.db); err != nil {
+		return err
+	}
+	return s.Load()
+}
+
+// Check key timings in the background until the server stops
+func (s *Signer) Start() {
+	go func() {
+		for {
+			s.Maintain()
+			time.Sleep(maintenanceInterval)
+		}
+	}()
+}
+
+// Remove keys past their removal time and start ZSK rollovers for keys that reached the end of their lifetime
+func (s *Signer) Maintain() {
+	stored, err := db.ListSigningKeys("", s.db)
+	if err != nil {
+		log.Printf("Failed to retrieve signing keys: %v", err)
+		return
+	}
+
+	now := time.Now()
+	zones := make(map[string]bool)
+	for _, key := range stored {
+		if key.Remove != 0 && key.Remove <= now.Unix() {
+			if err := db.DeleteSigningKey(key.Zone, key.Tag, s.db); err != nil {
+				log.Printf("Failed to remove key %d for zone '%s': %v", key.Tag, key.Zone, err)
+			} else {
+				log.Printf("Removed key %d for zone '%s'", key.Tag, key.Zone)
+			}
+			continue
+		}
+		zones[key.Zone] = true
+	}
+	if err := s.Load(); err != nil {
+		log.Printf("Failed to load signing keys: %v", err)
+		return
+	}
+
+	// A lifetime of 0 disables scheduled rollovers
+	if s.lifetime == 0 {
+		return
+	}
+
+	for zone := range zones {
+		// Skip zones with a rollover already in progress
+		current := s.current(zone, false, now)
+		if len(current) != 1 || s.pending(zone, now) {
+			continue
+		}
+
+		// Start early enough for the successor to be published for the full delay
+		if now.Before(current[0].Activate.Add(s.lifetime - s.delay)) {
+			continue
+		}
+
+		algorithm := "ecdsa"
+		for name, alg := range Algorithms {
+			if alg == current[0].DNSKEY.Algorithm {
+				algorithm = name
+			}
+		}
+
+		if key, err := s.Generate(zone, "zsk", algorithm); err != nil {
+			log.Printf("Failed to roll over ZSK for zone '%s': %v", zone, err)
+		} else {
+			log.Printf("Started rollover of ZSK %d to %d for zone '%s'", current[0].DNSKEY.KeyTag(), key.Tag, zone)
+		}
+	}
+}
+
+// Get the stored keys of a type that are active and not scheduled to retire
+func (s *Signer) current(zone string, ksk bool, now time.Time) []*Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []*Key
+	for _, key := range s.stored[db.NormalizeZone(zone)] {
+		if key.KSK() == ksk && key.Active(now) && key.Retire.IsZero() {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Check if a zone has a stored ZSK waiting to become active
+func (s *Signer) pending(zone string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.stored[db.NormalizeZone(zone)] {
+		if !key.KSK() && now.Before(key.Activate) {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert a stored key into one that can sign, decrypting the private key
+func (s *Signer) decode(k db.SigningKey) (*Key, error) {
+	plaintext, err := decrypt(k.PrivateKey, s.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	dnskey := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: dns.Fqdn(k.Zone), Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET},
+		Flags:     k.Flags,
+		Protocol:  3,
+		Algorithm: k.Algorithm,
+		PublicKey: k.PublicKey,
+	}
+	private, err := dnskey.NewPrivateKey(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("stored private key cannot sign")
+	}
+
+	return &Key{
+		Zone:     k.Zone,
+		DNSKEY:   dnskey,
+		Private:  signer,
+		Publish:  timestamp(k.Publish),
+		Activate: timestamp(k.Activate),
+		Retire:   timestamp(k.Retire),
+		Remove:   timestamp(k.Remove),
+	}, nil
+}
+
+// Convert a stored unix time, leaving unscheduled events as the zero time
+func timestamp(unix int64) time.Time {
+	if unix == 0 {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
+}
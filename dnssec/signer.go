@@ -0,0 +1,218 @@
+package dnssec
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/authority"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Maximum number of signatures kept before the cache is cleared
+const maxCachedSignatures = 10000
+
+// Signs responses from zones that have keys, caching signatures until they need refreshing
+type Signer struct {
+	mu         sync.Mutex
+	keys       map[string][]*Key
+	stored     map[string][]*Key
+	signatures map[string]*dns.RRSIG
+	validity   time.Duration
+	lifetime   time.Duration
+	delay      time.Duration
+	secret     string
+	db         *bolt.DB
+}
+
+// Create a signer whose signatures are valid for the given duration.
+// Keys stored in the database are rolled over after their lifetime, and are published for the delay before being used.
+func New(validity, lifetime, delay time.Duration, secret string, database *bolt.DB) *Signer {
+	return &Signer{
+		keys:       make(map[string][]*Key),
+		stored:     make(map[string][]*Key),
+		signatures: make(map[string]*dns.RRSIG),
+		validity:   validity,
+		lifetime:   lifetime,
+		delay:      delay,
+		secret:     secret,
+		db:         database,
+	}
+}
+
+// Add a key to sign its zone with
+func (s *Signer) AddKey(key *Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.Zone] = append(s.keys[key.Zone], key)
+}
+
+// Get every key of a zone, whether loaded from a file or stored in the database
+func (s *Signer) AllKeys(zone string) []*Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zone = db.NormalizeZone(zone)
+	return append(append([]*Key{}, s.keys[zone]...), s.stored[zone]...)
+}
+
+// Get the keys of a zone that currently sign records
+func (s *Signer) Keys(zone string) []*Key {
+	var keys []*Key
+	now := time.Now()
+	for _, key := range s.AllKeys(zone) {
+		if key.Active(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Check if a zone has keys to sign with
+func (s *Signer) Signed(zone string) bool {
+	return len(s.Keys(zone)) != 0
+}
+
+// Get the DNSKEY records to publish at the apex of a zone
+func (s *Signer) DNSKEYs(zone *db.Zone) []dns.RR {
+	var records []dns.RR
+	now := time.Now()
+	for _, key := range s.AllKeys(zone.Name) {
+		if !key.Published(now) {
+			continue
+		}
+
+		dnskey := *key.DNSKEY
+		dnskey.Hdr = dns.RR_Header{Name: dns.Fqdn(zone.Name), Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: authority.TTL(0, zone)}
+		records = append(records, &dnskey)
+	}
+	return records
+}
+
+// Add signatures to every RRset in a response that belongs to a signed zone
+func (s *Signer) Sign(r *dns.Msg) {
+	r.Answer = s.signSection(r.Answer)
+	r.Ns = s.signSection(r.Ns)
+	r.Extra = s.signSection(r.Extra)
+}
+
+// Sign the RRsets of a single section
+func (s *Signer) signSection(section []dns.RR) []dns.RR {
+	// Group records into RRsets, keeping the order they were added in
+	var order []string
+	rrsets := make(map[string][]dns.RR)
+	for _, rr := range section {
+		switch rr.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeOPT, dns.TypeTSIG:
+			continue
+		}
+
+		k := strings.ToLower(rr.Header().Name) + "/" + dns.TypeToString[rr.Header().Rrtype]
+		if _, ok := rrsets[k]; !ok {
+			order = append(order, k)
+		}
+		rrsets[k] = append(rrsets[k], rr)
+	}
+
+	for _, k := range order {
+		rrset := rrsets[k]
+		zone, err := db.FindZone(rrset[0].Header().Name, s.db)
+		if err != nil {
+			log.Printf("Failed to find zone for '%s': %v", rrset[0].Header().Name, err)
+			continue
+		} else if zone == nil {
+			continue
+		}
+
+		// Records in an RRset must share a TTL to be signed
+		minTTL := rrset[0].Header().Ttl
+		for _, rr := range rrset {
+			if rr.Header().Ttl < minTTL {
+				minTTL = rr.Header().Ttl
+			}
+		}
+		for _, rr := range rrset {
+			rr.Header().Ttl = minTTL
+		}
+
+		for _, key := range s.signingKeys(zone.Name, rrset[0].Header().Rrtype) {
+			if rrsig := s.signature(key, zone, rrset); rrsig != nil {
+				section = append(section, rrsig)
+			}
+		}
+	}
+
+	return section
+}
+
+// Get the keys that sign an RRset, the DNSKEY set is signed by KSKs and everything else by ZSKs
+func (s *Signer) signingKeys(zone string, rrtype uint16) []*Key {
+	keys := s.Keys(zone)
+
+	var ksks, zsks []*Key
+	for _, key := range keys {
+		if key.KSK() {
+			ksks = append(ksks, key)
+		} else {
+			zsks = append(zsks, key)
+		}
+	}
+
+	// A single type of key signs everything
+	if len(ksks) == 0 || len(zsks) == 0 {
+		return keys
+	} else if rrtype == dns.TypeDNSKEY {
+		return ksks
+	}
+	return zsks
+}
+
+// Get a signature for an RRset, reusing a cached one if it is not close to expiring
+func (s *Signer) signature(key *Key, zone *db.Zone, rrset []dns.RR) *dns.RRSIG {
+	// The cache key covers the records themselves so changes are signed again
+	var rdata []string
+	for _, rr := range rrset {
+		rdata = append(rdata, strings.ToLower(rr.String()))
+	}
+	sort.Strings(rdata)
+	cacheKey := strings.Join(append([]string{key.Zone, strconv.Itoa(int(key.DNSKEY.Algorithm)), strconv.Itoa(int(key.DNSKEY.KeyTag()))}, rdata...), "\n")
+
+	// Signatures are refreshed once less than a quarter of their validity remains
+	now := time.Now()
+	refresh := uint32(now.Add(s.validity / 4).Unix())
+
+	s.mu.Lock()
+	if cached, ok := s.signatures[cacheKey]; ok && cached.Expiration > refresh {
+		s.mu.Unlock()
+		return dns.Copy(cached).(*dns.RRSIG)
+	}
+	s.mu.Unlock()
+
+	rrsig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Ttl: rrset[0].Header().Ttl},
+		Algorithm:  key.DNSKEY.Algorithm,
+		KeyTag:     key.DNSKEY.KeyTag(),
+		SignerName: dns.Fqdn(zone.Name),
+		// Allow for clocks that are behind
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(s.validity).Unix()),
+	}
+	if err := rrsig.Sign(key.Private, rrset); err != nil {
+		log.Printf("Failed to sign %s %s: %v", rrset[0].Header().Name, dns.TypeToString[rrset[0].Header().Rrtype], err)
+		return nil
+	}
+
+	s.mu.Lock()
+	if len(s.signatures) >= maxCachedSignatures {
+		s.signatures = make(map[string]*dns.RRSIG)
+	}
+	s.signatures[cacheKey] = rrsig
+	s.mu.Unlock()
+
+	return dns.Copy(rrsig).(*dns.RRSIG)
+}
@@ -0,0 +1,62 @@
+package dnssec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// Private keys are encrypted with AES-256-GCM before being written to the database,
+// using a key derived from the configured secret
+
+// Encrypt a private key, prefixing the nonce to the result
+func encrypt(plaintext, secret string) (string, error) {
+	gcm, err := newCipher(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+// Decrypt a private key encrypted with the same secret
+func decrypt(ciphertext, secret string) (string, error) {
+	gcm, err := newCipher(secret)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	} else if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted key is too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt key, check the key secret: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+func newCipher(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("a key secret must be configured to store private keys")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,87 @@
+package forwarders
+
+import (
+	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/upstream"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+// Handle the creation of forwarding rules
+func create(w http.ResponseWriter, r *http.Request, forwarders *upstream.Forwarders, database *bolt.DB) {
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, _ := util.ValidateBody(body, []string{"domain", "upstream"}, map[string]map[string]string{
+		"domain": {"type": "string", "required": "true"},
+		"upstream": {"type": "stringarray", "required": "true"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	rule := db.ForwardingRule{Domain: body["domain"].(string)}
+	rule.Upstream, _ = util.ConvertArrayToString(body["upstream"].([]interface{}))
+
+	// Check rule does not exist
+	if _, ok := forwarders.Get(rule.Domain); ok {
+		util.Responses.Error(w, http.StatusBadRequest, "specified forwarding rule already exists")
+		return
+	}
+
+	// Start forwarding before saving so invalid rules are rejected
+	if err := forwarders.Add(upstream.Rule{Domain: rule.Domain, Upstream: rule.Upstream}); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Save to database
+	if err := rule.Encode(database); err != nil {
+		forwarders.Remove(rule.Domain)
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write forwarding rule to database: "+err.Error())
+		return
+	}
+
+	util.Responses.Success(w)
+}
@@ -0,0 +1,58 @@
+package forwarders
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/upstream"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+func deleteRule(w http.ResponseWriter, r *http.Request, path string, forwarders *upstream.Forwarders, database *bolt.DB) {
+	// Validate initial request with type, path, and header
+	if r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "domain must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check user role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Rules from the configuration file would come back on restart
+	if rule, ok := forwarders.Get(r.URL.Path[len(path):]); ok && rule.Configured {
+		util.Responses.Error(w, http.StatusForbidden, "forwarding rules from the configuration file cannot be deleted")
+		return
+	}
+
+	// Delete rule from database
+	if err := db.DeleteForwardingRule(r.URL.Path[len(path):], database); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to delete forwarding rule: "+err.Error())
+		return
+	}
+	forwarders.Remove(r.URL.Path[len(path):])
+
+	util.Responses.Success(w)
+}
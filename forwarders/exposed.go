@@ -0,0 +1,45 @@
+package forwarders
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/upstream"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+// Handle requests regarding forwarding rules
+func AllForwardersHandler(forwarders *upstream.Forwarders, db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			list(w, r, forwarders, db)
+			return
+		case "POST":
+			create(w, r, forwarders, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests for methods regarding the forwarding rule of a single domain
+func SingleForwarderHandler(path string, forwarders *upstream.Forwarders, db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			read(w, r, path, forwarders, db)
+			return
+		case "PUT":
+			update(w, r, path, forwarders, db)
+			return
+		case "DELETE":
+			deleteRule(w, r, path, forwarders, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
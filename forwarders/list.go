@@ -0,0 +1,29 @@
+package forwarders
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/upstream"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+// Handle the listing of all forwarding rules, from both the configuration and the API
+func list(w http.ResponseWriter, r *http.Request, forwarders *upstream.Forwarders, database *bolt.DB) {
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	if _, err := db.TokenFromString(r.Header.Get("Authorization"), database); err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, forwarders.Rules())
+}
@@ -0,0 +1,37 @@
+package forwarders
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/upstream"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+func read(w http.ResponseWriter, r *http.Request, path string, forwarders *upstream.Forwarders, database *bolt.DB) {
+	// Validate initial request with type, path, and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "domain must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	if _, err := db.TokenFromString(r.Header.Get("Authorization"), database); err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	rule, ok := forwarders.Get(r.URL.Path[len(path):])
+	if !ok {
+		util.Responses.Error(w, http.StatusNotFound, "specified forwarding rule does not exist")
+		return
+	}
+
+	util.Responses.SuccessWithData(w, rule)
+}
@@ -0,0 +1,91 @@
+package forwarders
+
+import (
+	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/upstream"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+func update(w http.ResponseWriter, r *http.Request, path string, forwarders *upstream.Forwarders, database *bolt.DB) {
+	// Validate initial request with type, body exists, and headers
+	if r.Method != "PUT" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "domain must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check user role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, _ := util.ValidateBody(body, []string{"upstream"}, map[string]map[string]string{
+		"upstream": {"type": "stringarray", "required": "true"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	// Get the existing rule
+	existing, ok := forwarders.Get(r.URL.Path[len(path):])
+	if !ok {
+		util.Responses.Error(w, http.StatusBadRequest, "specified forwarding rule does not exist")
+		return
+	} else if existing.Configured {
+		util.Responses.Error(w, http.StatusForbidden, "forwarding rules from the configuration file cannot be changed")
+		return
+	}
+
+	rule := db.ForwardingRule{Domain: existing.Domain}
+	rule.Upstream, _ = util.ConvertArrayToString(body["upstream"].([]interface{}))
+
+	// Replace the rule before saving so invalid rules are rejected
+	if err := forwarders.Add(upstream.Rule{Domain: rule.Domain, Upstream: rule.Upstream}); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Save to database
+	if err := rule.Encode(database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write forwarding rule to database: "+err.Error())
+		return
+	}
+
+	util.Responses.Success(w)
+}
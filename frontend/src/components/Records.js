@@ -137,7 +137,7 @@ export default class extends Component {
             });
     };
     onEditSave = () => {
-        ApiRecords.Update(this.state.name, this.state.record, this.state.data, Authentication.getToken())
+        ApiRecords.Update(this.state.name, this.state.record, {id: this.state.editInitial.id, ...this.state.data}, Authentication.getToken())
             .then(() => this.props.addToast("Successfully modified record", `Record ${this.state.name} in ${this.state.record} had data changed`, "success"))
             .catch(err => {
                 switch (err.response.status) {
@@ -186,7 +186,7 @@ export default class extends Component {
                         icon: "pencil",
                         type: "icon",
                         onClick: (record) => ApiRecords.Read(record.name, record.type, Authentication.getToken()).then(res => {
-                            this.setState({name: record.name, record: record.type, editInitial: res.data});
+                            this.setState({name: record.name, record: record.type, editInitial: res.data[0]});
                             this.toggleEditModal();
                         }).catch(err => {
                             switch (err.response.status) {
@@ -0,0 +1,96 @@
+package keys
+
+import (
+	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/dnssec"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+// Handle the generation of keys
+func create(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB, signer *dnssec.Signer) {
+	// Validate initial request with request type, path, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "zone must be specified in path")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, valid := util.ValidateBody(body, []string{"type", "algorithm"}, map[string]map[string]string{
+		"type": {"type": "string", "required": "true"},
+		"algorithm": {"type": "string", "required": "false"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	// Check zone exists
+	if zone, err := db.GetZone(r.URL.Path[len(path):], database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve zone: "+err.Error())
+		return
+	} else if zone == nil {
+		util.Responses.Error(w, http.StatusNotFound, "specified zone does not exist")
+		return
+	}
+
+	algorithm := "ecdsa"
+	if valid["algorithm"] {
+		algorithm = body["algorithm"].(string)
+	}
+	if _, ok := dnssec.Algorithms[algorithm]; !ok {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'algorithm' must be one of 'ecdsa' or 'ed25519'")
+		return
+	} else if body["type"] != "ksk" && body["type"] != "zsk" {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'type' must be one of 'ksk' or 'zsk'")
+		return
+	}
+
+	// Generate and store the key
+	key, err := signer.Generate(r.URL.Path[len(path):], body["type"].(string), algorithm)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to generate key: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, map[string]interface{}{"tag": key.Tag, "activate": key.Activate})
+}
@@ -0,0 +1,28 @@
+package keys
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/dnssec"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+// Handle requests for the signing keys of a zone
+func KeysHandler(path string, db *bolt.DB, signer *dnssec.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			list(w, r, path, db, signer)
+			return
+		case "POST":
+			create(w, r, path, db, signer)
+			return
+		case "DELETE":
+			retire(w, r, path, db, signer)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
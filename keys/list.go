@@ -0,0 +1,99 @@
+package keys
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/dnssec"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"time"
+)
+
+// A signing key as returned by the API, without the private key
+type key struct {
+	Tag       uint16   `json:"tag"`
+	Type      string   `json:"type"`
+	Algorithm string   `json:"algorithm"`
+	State     string   `json:"state"`
+	Publish   int64    `json:"publish"`
+	Activate  int64    `json:"activate"`
+	Retire    int64    `json:"retire"`
+	Remove    int64    `json:"remove"`
+	DNSKEY    string   `json:"dnskey"`
+	DS        []string `json:"ds"`
+}
+
+func list(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB, signer *dnssec.Signer) {
+	// Validate initial request with type, path, and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "zone must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	if _, err := db.TokenFromString(r.Header.Get("Authorization"), database); err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Check zone exists
+	if zone, err := db.GetZone(r.URL.Path[len(path):], database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve zone: "+err.Error())
+		return
+	} else if zone == nil {
+		util.Responses.Error(w, http.StatusNotFound, "specified zone does not exist")
+		return
+	}
+
+	// Describe each key with the DS records for the parent zone
+	keys := []key{}
+	now := time.Now()
+	for _, k := range signer.AllKeys(r.URL.Path[len(path):]) {
+		described := key{
+			Tag:       k.DNSKEY.KeyTag(),
+			Type:      "zsk",
+			Algorithm: algorithmName(k.DNSKEY.Algorithm),
+			State:     k.State(now),
+			Publish:   unix(k.Publish),
+			Activate:  unix(k.Activate),
+			Retire:    unix(k.Retire),
+			Remove:    unix(k.Remove),
+			DNSKEY:    k.DNSKEY.String(),
+			DS:        []string{},
+		}
+		if k.KSK() {
+			described.Type = "ksk"
+		}
+		for _, ds := range dnssec.DS(k.DNSKEY) {
+			described.DS = append(described.DS, ds.String())
+		}
+
+		keys = append(keys, described)
+	}
+
+	util.Responses.SuccessWithData(w, keys)
+}
+
+// Get the name of an algorithm as accepted when generating keys
+func algorithmName(algorithm uint8) string {
+	for name, alg := range dnssec.Algorithms {
+		if alg == algorithm {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Convert a time to unix seconds, leaving unscheduled events as 0
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
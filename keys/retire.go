@@ -0,0 +1,61 @@
+package keys
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/dnssec"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handle retiring keys, which are removed once no longer cached
+func retire(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB, signer *dnssec.Signer) {
+	// Validate initial request with type, path, and header
+	parts := strings.SplitN(r.URL.Path[len(path):], "/", 2)
+	if r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "zone and key tag must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	tag, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "key tag must be a number")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check user role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Retire key
+	if err := signer.Retire(parts[0], uint16(tag)); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to retire key: "+err.Error())
+		return
+	}
+
+	util.Responses.Success(w)
+}
@@ -1,13 +1,27 @@
 package main
 
 import (
+	"crypto/tls"
+	"encoding/base64"
 	"flag"
+	"fmt"
 	rice "github.com/GeertJohan/go.rice"
+	"github.com/akrantz01/krantz.dev/dns/authority"
+	"github.com/akrantz01/krantz.dev/dns/blocklist"
+	"github.com/akrantz01/krantz.dev/dns/cache"
 	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/dnssec"
+	"github.com/akrantz01/krantz.dev/dns/forwarders"
+	"github.com/akrantz01/krantz.dev/dns/keys"
+	"github.com/akrantz01/krantz.dev/dns/ratelimit"
 	"github.com/akrantz01/krantz.dev/dns/records"
 	"github.com/akrantz01/krantz.dev/dns/roles"
+	"github.com/akrantz01/krantz.dev/dns/secondary"
+	"github.com/akrantz01/krantz.dev/dns/upstream"
 	"github.com/akrantz01/krantz.dev/dns/users"
 	"github.com/akrantz01/krantz.dev/dns/util"
+	"github.com/akrantz01/krantz.dev/dns/views"
+	"github.com/akrantz01/krantz.dev/dns/zones"
 	"github.com/gorilla/handlers"
 	"github.com/miekg/dns"
 	"github.com/rs/cors"
@@ -15,194 +29,519 @@ import (
 	"github.com/spf13/viper"
 	bolt "go.etcd.io/bbolt"
 	"gopkg.in/hlandau/passlib.v1"
+	"io"
+	"io/ioutil"
 	"log"
-	"math/rand"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
 var database *bolt.DB
+var responses *cache.Cache
+var upstreams *upstream.Forwarders
+var secondaries *secondary.Manager
+var signer *dnssec.Signer
+var clientViews *views.Views
+var subnets *upstream.Subnets
+var blocker *blocklist.Blocker
+var limiters = make(map[string]*ratelimit.Limiter)
+var queryACL, recursionACL *util.ACL
+
+// Clients allowed to do something, by network or TSIG key
+type aclConfig struct {
+	Networks []string `mapstructure:"networks"`
+	Keys     []string `mapstructure:"tsig-keys"`
+}
+var keyRoles = make(map[string]string)
+
+// A TSIG key clients can sign queries with
+type tsigKey struct {
+	Name   string `mapstructure:"name"`
+	Secret string `mapstructure:"secret"`
+	Role   string `mapstructure:"role"`
+}
+
+// Maximum number of CNAMEs to follow through our own records
+const maxCNAMEDepth = 8
+
+// Answers queries for a single listener, with its own rate limits
+type handler struct {
+	limiter *ratelimit.Limiter
+}
 
-type handler struct {}
 func (h *handler) ServeDNS(w dns.ResponseWriter, m *dns.Msg) {
+	// Time request for logging
+	start := time.Now()
+
+	// Clients sending too many queries are ignored
+	client := addressIP(w.RemoteAddr())
+	if !h.limiter.AllowQuery(client) {
+		return
+	}
+
+	// Primaries announce changes to secondary zones with NOTIFY
+	if m.Opcode == dns.OpcodeNotify {
+		r := new(dns.Msg)
+		r.SetReply(m)
+		r.Authoritative = true
+		if len(m.Question) != 1 || !secondaries.Notify(m.Question[0].Name, w, m) {
+			r.Rcode = dns.RcodeRefused
+		}
+
+		if tsig := m.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+			r.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+		}
+		if err := w.WriteMsg(r); err != nil {
+			log.Printf("Unable to send response: %v", err)
+		}
+		util.LogResponse(w, r, start)
+		return
+	}
+
+	// Dynamic updates change records instead of querying them
+	if m.Opcode == dns.OpcodeUpdate {
+		r := authority.Update(w, m, keyRoles, database)
+		if err := w.WriteMsg(r); err != nil {
+			log.Printf("Unable to send response: %v", err)
+		}
+		util.LogResponse(w, r, start)
+		return
+	}
+
+	// Zone transfers are streamed rather than answered with a single message
+	if len(m.Question) == 1 && (m.Question[0].Qtype == dns.TypeAXFR || m.Question[0].Qtype == dns.TypeIXFR) {
+		authority.Transfer(w, m, database)
+		log.Printf("%s transfer of %s requested by %s", dns.TypeToString[m.Question[0].Qtype], m.Question[0].Name, w.RemoteAddr())
+		return
+	}
+
+	// Clients can be identified by the TSIG key they signed with
+	var key string
+	if tsig := m.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		key = tsig.Hdr.Name
+	}
+	r := resolve(m, client, key)
+
+	// Limit identical responses so the server cannot be used for reflection, which is only possible over UDP
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		switch h.limiter.Response(client, r) {
+		case ratelimit.Drop:
+			return
+		case ratelimit.Slip:
+			slipped := new(dns.Msg)
+			slipped.SetReply(m)
+			slipped.Truncated = true
+			slipped.Rcode = r.Rcode
+			r = slipped
+		}
+	}
+
+	// Responses over UDP must fit the buffer the client advertised, larger ones are truncated for the client to retry over TCP
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		size := udpSize(m)
+		if tsig := m.IsTsig(); tsig != nil {
+			size -= dns.Len(tsig)
+		}
+		trimAdditional(r, size)
+		r.Truncate(size)
+	}
+
+	// Sign the response with the key the query was signed with
+	if tsig := m.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		r.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+	}
+
+	// Write response
+	if err := w.WriteMsg(r); err != nil {
+		log.Printf("Unable to send response: %v", err)
+	}
+
+	// Log to console
+	util.LogResponse(w, r, start)
+}
+
+// Build the response to a query for a client identified by its address and TSIG key, shared by all transports
+func resolve(m *dns.Msg, client net.IP, key string) *dns.Msg {
 	// Set database into getter and setter
 	db.Get.Db = database
 	db.Set.Db = database
 
-	// Time request for logging
-	start := time.Now()
-
 	// Assemble response
 	r := new(dns.Msg)
 	r.SetReply(m)
-	r.Authoritative = true
-	r.RecursionAvailable = true
+	r.RecursionAvailable = recursionACL.Allowed(client, key)
+
+	// Only queries can be resolved, other operations are handled by ServeDNS
+	if m.Opcode != dns.OpcodeQuery {
+		r.Rcode = dns.RcodeNotImplemented
+		return r
+	}
+
+	// Clients not allowed to query are refused outright
+	if !queryACL.Empty() && !queryACL.Allowed(client, key) {
+		r.Rcode = dns.RcodeRefused
+		return r
+	}
+
+	// Only version 0 of EDNS exists, later versions are rejected as described in RFC 6891
+	opt := m.IsEdns0()
+	if opt != nil && opt.Version() != 0 {
+		r.SetEdns0(uint16(viper.GetInt("dns.max-udp-size")), false)
+		r.Rcode = dns.RcodeBadVers
+		return r
+	}
+
+	// Clients are answered from the view matching their address or TSIG key
+	view := clientViews.Select(client, key)
+	data := db.Get.In(view)
+
+	// Only clients that ask for recursion and are allowed to get it are answered from upstream
+	recursion := m.RecursionDesired && r.RecursionAvailable
+
+	// Resolvers that validate ask for signatures with the DO bit
+	do := opt != nil && opt.Do()
+
+	// Upstream servers are told roughly where the client is, and the scope they answer with is passed back
+	subnet := subnets.Option(client, m)
+	var scope uint8
+
+	// Answers from upstream are passed on without adding anything of our own
+	forwarded := false
+
+	// Only answers from our own zones are authoritative, blocked names are answered in place of their owners
+	authoritative, blocked := false, false
 
 	// Iterate over all questions
 	for _, q := range r.Question {
-		var recordFound bool
-		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: q.Qclass}
-
-		// Do different things based on record type
-		switch q.Qtype {
-		case dns.TypeA:
-			record := db.Get.A(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.A{Hdr: hdr, A: record.Address})
-			}
-		case dns.TypeAAAA:
-			record :=  db.Get.AAAA(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.AAAA{Hdr: hdr, AAAA: record.Address})
-			}
-		case dns.TypeCNAME:
-			record :=  db.Get.CNAME(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.CNAME{Hdr: hdr, Target: record.Target})
+		// Zone transfers must be made directly over TCP or TLS
+		if q.Qtype == dns.TypeAXFR || q.Qtype == dns.TypeIXFR {
+			r.Rcode = dns.RcodeRefused
+			continue
+		}
+
+		// Records are stored lowercase, while answers keep the case the client asked with
+		owner := q.Name
+		qname := strings.ToLower(q.Name)
+
+		// Follow CNAMEs through our own records until an answer is found
+		visited := make(map[string]bool)
+		for depth := 0; ; depth++ {
+			visited[qname] = true
+
+			// Find the zone the name is in, if any
+			zone, err := db.FindZone(qname, database)
+			if err != nil {
+				log.Printf("Failed to find zone for '%s': %v", qname, err)
 			}
-		case dns.TypeMX:
-			record :=  db.Get.MX(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.MX{Hdr: hdr, Preference: record.Priority, Mx: record.Host})
+			apex := zone != nil && qname == dns.Fqdn(zone.Name)
+
+			// Secondary zones that could not be refreshed in time are no longer trusted
+			if zone != nil && secondaries.Expired(zone.Name) {
+				r.Rcode = dns.RcodeServerFailure
+				break
 			}
-		case dns.TypeLOC:
-			record :=  db.Get.LOC(q.Name)
-			if record != nil {
-				recordFound = true
-				locString, vers := record.ToParsable()
-				r.Answer = append(r.Answer, util.ParseLOCString(locString, vers, hdr))
+			if zone != nil {
+				authoritative = true
 			}
-		case dns.TypeSRV:
-			record :=  db.Get.SRV(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.SRV{Hdr: hdr, Priority: record.Priority, Weight: record.Weight, Port: record.Port, Target: record.Target})
+
+			// Names that do not exist are answered from a matching wildcard with the owner left as queried
+			name := qname
+			if wildcard := data.Wildcard(qname); wildcard != "" {
+				name = wildcard
 			}
-		case dns.TypeSPF:
-			record :=  db.Get.SPF(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.SPF{Hdr: hdr, Txt: record.Text})
+
+			// ANY queries for names we host are answered here and never forwarded, as described in RFC 8482
+			if q.Qtype == dns.TypeANY && (zone != nil || data.NameExists(name)) {
+				answers := authority.AnyView(view, owner, name, q.Qclass, zone, viper.GetString("dns.any-mode"))
+				if apex && viper.GetString("dns.any-mode") == authority.AnyAll {
+					answers = append(answers, signer.DNSKEYs(zone)...)
+				}
+				if len(answers) != 0 {
+					r.Answer = append(r.Answer, answers...)
+					break
+				} else if zone == nil {
+					break
+				}
 			}
-		case dns.TypeTXT:
-			record :=  db.Get.TXT(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.TXT{Hdr: hdr, Txt: record.Text})
+
+			answers := authority.LookupView(view, owner, name, q.Qtype, q.Qclass, zone)
+			if apex && q.Qtype == dns.TypeDNSKEY {
+				answers = append(answers, signer.DNSKEYs(zone)...)
 			}
-		case dns.TypeNS:
-			record :=  db.Get.NS(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.NS{Hdr: hdr, Ns: record.Nameserver})
+			if len(answers) != 0 {
+				r.Answer = append(r.Answer, answers...)
+				break
 			}
-		case dns.TypeCAA:
-			record :=  db.Get.CAA(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.CAA{Hdr: hdr, Flag: record.Flag, Tag: record.Tag, Value: record.Content})
+
+			// Names with a CNAME are answered with the alias and its target
+			if cnames := data.CNAME(name); len(cnames) != 0 && q.Qtype != dns.TypeCNAME {
+				target := dns.Fqdn(strings.ToLower(cnames[0].Target))
+				r.Answer = append(r.Answer, &dns.CNAME{Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeCNAME, Class: q.Qclass, Ttl: authority.TTL(cnames[0].TTL, zone)}, Target: target})
+
+				// Stop following chains that loop or are too long
+				if visited[target] || depth >= maxCNAMEDepth {
+					r.Rcode = dns.RcodeServerFailure
+					break
+				}
+
+				qname, owner = target, target
+				continue
 			}
-		case dns.TypePTR:
-			record := db.Get.PTR(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.PTR{Hdr: hdr, Ptr: record.Domain})
+
+			if zone != nil {
+				// Names within our zones are answered without recursing, names with records
+				// below them exist even without records of their own
+				nxdomain := !apex && !data.NameExists(name)
+				if nxdomain {
+					r.Rcode = dns.RcodeNameError
+				}
+
+				// Negative answers are cached for the lesser of the SOA TTL and minimum
+				soa := authority.SOA(zone)
+				if soa.Minttl < soa.Hdr.Ttl {
+					soa.Hdr.Ttl = soa.Minttl
+				}
+				r.Ns = append(r.Ns, soa)
+
+				// Prove the name or type does not exist in signed zones
+				if do && signer.Signed(zone.Name) {
+					r.Ns = append(r.Ns, signer.Deny(view, qname, name, zone, nxdomain)...)
+				}
+				break
 			}
-		case dns.TypeCERT:
-			record :=  db.Get.CERT(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.CERT{Hdr: hdr, Type: record.Type, KeyTag: record.KeyTag, Algorithm: record.Algorithm, Certificate: record.Certificate})
+
+			// Names we have records for outside of any zone exist, but have no data of this type
+			if data.NameExists(name) {
+				break
 			}
-		case dns.TypeDNSKEY:
-			record :=  db.Get.DNSKEY(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.DNSKEY{Hdr: hdr, Flags: record.Flags, Protocol: record.Protocol, Algorithm: record.Algorithm, PublicKey: record.PublicKey})
+
+			// Names outside our zones are refused without recursion, unless reached through a CNAME
+			if !recursion {
+				if depth == 0 {
+					r.Rcode = dns.RcodeRefused
+				}
+				break
 			}
-		case dns.TypeDS:
-			record :=  db.Get.DS(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.DS{Hdr: hdr, KeyTag: record.KeyTag, Algorithm: record.Algorithm, DigestType: record.DigestType, Digest: record.Digest})
+
+			// Answer blocked names without looking them up
+			if blocker.Answer(r, dns.Question{Name: qname, Qtype: q.Qtype, Qclass: q.Qclass}) {
+				blocked = true
+				break
 			}
-		case dns.TypeNAPTR:
-			record :=  db.Get.NAPTR(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.NAPTR{Hdr: hdr, Order: record.Order, Preference: record.Preference, Flags: record.Flags, Service: record.Service, Regexp: record.Regexp, Replacement: record.Replacement})
+
+			// Look up recursively, unless already cached
+			resp := responses.Get(qname, q.Qtype, q.Qclass, subnet)
+			if resp == nil {
+				recursMsg := new(dns.Msg)
+				recursMsg.SetQuestion(dns.Fqdn(qname), q.Qtype)
+				recursMsg.SetEdns0(4096, true)
+				recursMsg.RecursionDesired = true
+				if subnet != nil {
+					recursOpt := recursMsg.IsEdns0()
+					recursOpt.Option = append(recursOpt.Option, subnet)
+				}
+
+				// Send to the upstream resolvers for the name
+				resp, err = upstreams.For(qname).Exchange(recursMsg)
+				if err != nil {
+					log.Printf("Failed to resolve '%s' upstream: %v", qname, err)
+					r.Rcode = dns.RcodeServerFailure
+					break
+				}
+				responses.Set(resp, subnet)
 			}
-		case dns.TypeSMIMEA:
-			record :=  db.Get.SMIMEA(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.SMIMEA{Hdr: hdr, Usage: record.Usage, Selector: record.Selector, MatchingType: record.MatchingType, Certificate: record.Certificate})
+			if ecs := upstream.SubnetOption(resp); subnet != nil && ecs != nil && ecs.SourceScope > scope {
+				scope = ecs.SourceScope
 			}
-		case dns.TypeSSHFP:
-			record :=  db.Get.SSHFP(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.SSHFP{Hdr: hdr, Algorithm: record.Algorithm, Type: record.Type, FingerPrint: record.Fingerprint})
+			forwarded = true
+
+			// Add new responses
+			if resp.Rcode != dns.RcodeSuccess {
+				r.Rcode = resp.Rcode
 			}
-		case dns.TypeTLSA:
-			record :=  db.Get.TLSA(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.TLSA{Hdr: hdr, Usage: record.Usage, Selector: record.Selector, MatchingType: record.MatchingType, Certificate: record.Certificate})
+			// Signatures from upstream are only passed on to clients that asked for them
+			if do {
+				r.Answer = append(r.Answer, resp.Answer...)
+			} else {
+				r.Answer = append(r.Answer, unsigned(resp.Answer, q.Qtype)...)
 			}
-		case dns.TypeURI:
-			record :=  db.Get.URI(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.URI{Hdr: hdr, Priority: record.Priority, Weight: record.Weight, Target: record.Target})
+
+			// Negative answers keep the SOA they are cached by
+			if len(resp.Answer) == 0 {
+				for _, rr := range resp.Ns {
+					if rr.Header().Rrtype == dns.TypeSOA {
+						r.Ns = append(r.Ns, rr)
+					}
+				}
 			}
-		default:
-			recordFound = false
+			break
 		}
+	}
+
+	// Answers that were refused, failed, or include data from elsewhere are not authoritative
+	r.Authoritative = authoritative && !forwarded && !blocked && r.Rcode != dns.RcodeRefused && r.Rcode != dns.RcodeServerFailure
 
-		if !recordFound {
-			// Look up recursively
-			recursMsg := new(dns.Msg)
-			recursMsg.SetQuestion(dns.Fqdn(q.Name), q.Qtype)
-			recursMsg.SetEdns0(4096, true)
-			recursMsg.RecursionDesired = true
+	// Add the addresses of targets we host to our own answers, so clients do not have to ask for them
+	if !forwarded {
+		r.Extra = append(r.Extra, authority.AdditionalView(view, r.Answer, database)...)
+	}
 
-			// Get random upstream resolver
-			rand.Seed(time.Now().UnixNano())
-			resolvers := viper.GetStringSlice("dns.upstream")
+	// Sign records from our zones for resolvers that validate
+	if do {
+		signer.Sign(r)
+	}
 
-			// Send new response
-			resp, err := dns.Exchange(recursMsg, resolvers[rand.Intn(len(resolvers))])
-			if err != nil {
-				r.Rcode = dns.RcodeNameError
+	// Clients that speak EDNS are told the largest response we send over UDP, and whether it is signed
+	if opt != nil {
+		r.SetEdns0(uint16(viper.GetInt("dns.max-udp-size")), do)
+	}
+
+	// Clients that sent a subnet are told the scope the answer applies to
+	if given := upstream.SubnetOption(m); given != nil {
+		replyOpt := r.IsEdns0()
+		replyOpt.Option = append(replyOpt.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: given.Family, SourceNetmask: given.SourceNetmask, SourceScope: scope, Address: given.Address})
+	}
+
+	return r
+}
+
+// Handle DNS over HTTPS queries as described in RFC 8484
+func dnsOverHTTPS(w http.ResponseWriter, r *http.Request) {
+	// Read the query from the method specific location
+	var query []byte
+	var err error
+	switch r.Method {
+	case "GET":
+		if r.URL.Query().Get("dns") == "" {
+			http.Error(w, "query parameter 'dns' is required", http.StatusBadRequest)
+			return
+		}
+		query, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+	case "POST":
+		if r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "body must be of type application/dns-message", http.StatusUnsupportedMediaType)
+			return
+		}
+		query, err = ioutil.ReadAll(io.LimitReader(r.Body, dns.MaxMsgSize))
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to read query: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m := new(dns.Msg)
+	if err := m.Unpack(query); err != nil {
+		http.Error(w, "failed to parse query: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Clients are identified by address only, as HTTP queries are not signed
+	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+	reply := resolve(m, net.ParseIP(host), "")
+	response, err := reply.Pack()
+	if err != nil {
+		http.Error(w, "failed to build response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/dns-message")
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge(reply)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(response); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// Get how long an HTTP response can be cached for from the lowest TTL of its records
+func maxAge(m *dns.Msg) uint32 {
+	var age uint32
+	found := false
+	for _, section := range [][]dns.RR{m.Answer, m.Ns} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
 				continue
 			}
 
-			// Add new responses
-			if resp.Rcode != dns.RcodeSuccess {
-				r.Rcode = resp.Rcode
+			ttl := rr.Header().Ttl
+			if soa, ok := rr.(*dns.SOA); ok && soa.Minttl < ttl {
+				ttl = soa.Minttl
+			}
+			if !found || ttl < age {
+				age = ttl
+				found = true
 			}
-			r.Answer = append(r.Answer, resp.Answer...)
 		}
 	}
+	return age
+}
 
-	// Throw error if no answers
-	if len(r.Answer) == 0 {
-		r.Rcode = dns.RcodeNameError
+// Get the IP address of a client
+func addressIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
 	}
+	return nil
+}
 
-	// Write response
-	if err := w.WriteMsg(r); err != nil {
-		log.Printf("Unable to send response: %v", err)
+// Get the largest response a client can take over UDP, limited by the configured maximum
+func udpSize(m *dns.Msg) int {
+	size := dns.MinMsgSize
+	if opt := m.IsEdns0(); opt != nil && int(opt.UDPSize()) > size {
+		size = int(opt.UDPSize())
+	}
+	if max := viper.GetInt("dns.max-udp-size"); size > max {
+		size = max
 	}
+	return size
+}
 
-	// Log to console
-	util.LogResponse(w, r, start)
+// Drop additional records until a response fits, since unlike answers they are optional and do not make the response truncated.
+// Records are dropped a name at a time from the end, so signatures go with the records they cover.
+func trimAdditional(r *dns.Msg, size int) {
+	r.Compress = true
+	for r.Len() > size {
+		var last string
+		for _, rr := range r.Extra {
+			if rr.Header().Rrtype != dns.TypeOPT && rr.Header().Rrtype != dns.TypeTSIG {
+				last = strings.ToLower(rr.Header().Name)
+			}
+		}
+		if last == "" {
+			return
+		}
+
+		var kept []dns.RR
+		for _, rr := range r.Extra {
+			if strings.ToLower(rr.Header().Name) != last || rr.Header().Rrtype == dns.TypeOPT || rr.Header().Rrtype == dns.TypeTSIG {
+				kept = append(kept, rr)
+			}
+		}
+		r.Extra = kept
+	}
+}
+
+// Remove DNSSEC records from an answer for clients that did not ask for them, unless they were queried for directly
+func unsigned(records []dns.RR, qtype uint16) []dns.RR {
+	var filtered []dns.RR
+	for _, rr := range records {
+		switch rr.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			if rr.Header().Rrtype != qtype {
+				continue
+			}
+		}
+		filtered = append(filtered, rr)
+	}
+	return filtered
 }
 
 func queryDNS(q string, t uint16) ([]dns.RR, int) {
@@ -210,12 +549,9 @@ func queryDNS(q string, t uint16) ([]dns.RR, int) {
 	msg.SetQuestion(dns.Fqdn(q), t)
 	msg.SetEdns0(4096, true)
 
-	c := new(dns.Client)
-	rand.Seed(time.Now().UnixNano())
-	resolvers := viper.GetStringSlice("dns.upstream")
-	in, _, err := c.Exchange(msg, resolvers[rand.Intn(len(resolvers))])
+	in, err := upstreams.For(q).Exchange(msg)
 	if err != nil {
-		return []dns.RR{}, dns.RcodeNameError
+		return []dns.RR{}, dns.RcodeServerFailure
 	}
 	return in.Answer, dns.RcodeSuccess
 }
@@ -236,6 +572,33 @@ func main() {
 	flag.String("dns.database", "./records.db", "Database file to use")
 	flag.Bool("dns.disable-tcp", false, "Disable listening on TCP")
 	flag.Bool("dns.disable-udp", false, "Disable listening on UDP")
+	flag.Bool("dns.disable-tls", true, "Disable listening on DNS over TLS")
+	flag.Int("dns.tls-port", 853, "Port for DNS over TLS to listen on")
+	flag.String("dns.tls-cert", "", "Certificate file for DNS over TLS")
+	flag.String("dns.tls-key", "", "Private key file for DNS over TLS")
+	flag.Uint("dns.ttl", 300, "Default TTL in seconds for records without one")
+	flag.String("dns.upstream-selection", "random", "How to pick upstream resolvers: random, round-robin, or lowest-latency")
+	flag.Duration("dns.upstream-timeout", 2*time.Second, "Time to wait for an upstream resolver to answer")
+	flag.Int("dns.upstream-failures", 3, "Consecutive failures before an upstream resolver is taken out of rotation")
+	flag.Duration("dns.upstream-cooldown", 30*time.Second, "Time a failing upstream resolver is kept out of rotation")
+	flag.String("dns.recursion-mode", "forward", "How to resolve names outside our zones: forward to upstream resolvers, or iterative from the root servers")
+	flag.Bool("dns.qname-minimisation", true, "Only send nameservers as much of the name as they need when resolving iteratively")
+	flag.Int("dns.nameserver-port", 53, "Port nameservers are contacted on when resolving iteratively")
+	flag.String("dns.any-mode", "hinfo", "How to answer ANY queries for names we host: hinfo, or all to return every record")
+	flag.Int("dns.max-udp-size", 1232, "Largest response in bytes sent over UDP, whatever buffer size clients advertise")
+	flag.Int("dns.cache-size", 10000, "Maximum number of upstream responses to cache, 0 to disable")
+	flag.Bool("dns.ecs.enabled", false, "Send the client's subnet to upstream resolvers")
+	flag.Int("dns.ecs.ipv4-prefix", 24, "Number of bits of IPv4 client addresses sent upstream")
+	flag.Int("dns.ecs.ipv6-prefix", 56, "Number of bits of IPv6 client addresses sent upstream")
+	flag.String("dns.ecs.override", "", "Subnet to send upstream in place of every client's address")
+	flag.String("dns.blocklist.action", "nxdomain", "How to answer blocked names: nxdomain, nodata, or sinkhole")
+	flag.String("dns.blocklist.sinkhole-ipv4", "0.0.0.0", "Address to answer blocked A queries with when sinkholing")
+	flag.String("dns.blocklist.sinkhole-ipv6", "::", "Address to answer blocked AAAA queries with when sinkholing")
+	flag.Duration("dns.blocklist.refresh", 24*time.Hour, "Time between reloading blocklists from their files, 0 to disable")
+	flag.Duration("dns.dnssec.signature-validity", 7*24*time.Hour, "Time signatures made by the server are valid for")
+	flag.Duration("dns.dnssec.zsk-lifetime", 90*24*time.Hour, "Time a generated ZSK is used before being rolled over, 0 to disable")
+	flag.Duration("dns.dnssec.rollover-delay", 24*time.Hour, "Time new keys are published before use and old keys are kept after retirement")
+	flag.String("dns.dnssec.key-secret", "", "Secret generated private keys are encrypted with in the database")
 	flag.String("http.host", "127.0.0.1", "IP address to run the API on")
 	flag.Int("http.port", 8080, "Port for the API to listen on")
 	flag.String("http.admin.name", "DNS Admin", "Name of the admin user")
@@ -253,7 +616,36 @@ func main() {
 	viper.SetDefault("dns.database", "./records.db")
 	viper.SetDefault("dns.disable-tcp", false)
 	viper.SetDefault("dns.disable-udp", false)
+	viper.SetDefault("dns.disable-tls", true)
+	viper.SetDefault("dns.tls-port", 853)
+	viper.SetDefault("dns.tls-cert", "")
+	viper.SetDefault("dns.tls-key", "")
 	viper.SetDefault("dns.upstream", []string{"1.1.1.1:53", "8.8.8.8:53"})
+	viper.SetDefault("dns.ttl", 300)
+	viper.SetDefault("dns.upstream-selection", "random")
+	viper.SetDefault("dns.upstream-timeout", "2s")
+	viper.SetDefault("dns.upstream-failures", 3)
+	viper.SetDefault("dns.upstream-cooldown", "30s")
+	viper.SetDefault("dns.recursion-mode", "forward")
+	viper.SetDefault("dns.root-hints", []string{})
+	viper.SetDefault("dns.qname-minimisation", true)
+	viper.SetDefault("dns.nameserver-port", 53)
+	viper.SetDefault("dns.any-mode", "hinfo")
+	viper.SetDefault("dns.max-udp-size", 1232)
+	viper.SetDefault("dns.cache-size", 10000)
+	viper.SetDefault("dns.acl.recursion.networks", []string{"127.0.0.0/8", "::1", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"})
+	viper.SetDefault("dns.ecs.enabled", false)
+	viper.SetDefault("dns.ecs.ipv4-prefix", 24)
+	viper.SetDefault("dns.ecs.ipv6-prefix", 56)
+	viper.SetDefault("dns.ecs.override", "")
+	viper.SetDefault("dns.blocklist.action", "nxdomain")
+	viper.SetDefault("dns.blocklist.sinkhole-ipv4", "0.0.0.0")
+	viper.SetDefault("dns.blocklist.sinkhole-ipv6", "::")
+	viper.SetDefault("dns.blocklist.refresh", "24h")
+	viper.SetDefault("dns.dnssec.signature-validity", "168h")
+	viper.SetDefault("dns.dnssec.zsk-lifetime", "2160h")
+	viper.SetDefault("dns.dnssec.rollover-delay", "24h")
+	viper.SetDefault("dns.dnssec.key-secret", "")
 
 	viper.SetDefault("http.host", "127.0.0.1")
 	viper.SetDefault("http.port", 8080)
@@ -286,20 +678,181 @@ func main() {
 		log.Fatalf("Failed setting up database structure: %v", err)
 	}
 
+	// Set database into operations used outside of requests
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	// Load TSIG keys for signed queries and transfers
+	var tsigKeys []tsigKey
+	if err := viper.UnmarshalKey("dns.tsig-keys", &tsigKeys); err != nil {
+		log.Fatalf("Invalid TSIG key configuration: %v", err)
+	}
+	tsigSecrets := make(map[string]string)
+	for _, key := range tsigKeys {
+		tsigSecrets[dns.Fqdn(strings.ToLower(key.Name))] = key.Secret
+		keyRoles[dns.Fqdn(strings.ToLower(key.Name))] = key.Role
+	}
+
+	// Journal every zone so changes made while stopped get a new serial
+	allZones, err := db.ListZones(database)
+	if err != nil {
+		log.Fatalf("Failed to retrieve zones: %v", err)
+	}
+	for _, zone := range allZones {
+		if err := authority.Journal(zone.Name, database); err != nil {
+			log.Printf("Failed to journal zone '%s': %v", zone.Name, err)
+		}
+	}
+
+	// Setup who may query and who may use recursion
+	var queryClients, recursionClients aclConfig
+	if err := viper.UnmarshalKey("dns.acl.query", &queryClients); err != nil {
+		log.Fatalf("Invalid query ACL configuration: %v", err)
+	}
+	if err := viper.UnmarshalKey("dns.acl.recursion", &recursionClients); err != nil {
+		log.Fatalf("Invalid recursion ACL configuration: %v", err)
+	}
+	if queryACL, err = util.NewACL(queryClients.Networks, queryClients.Keys); err != nil {
+		log.Fatalf("Invalid query ACL configuration: %v", err)
+	}
+	if recursionACL, err = util.NewACL(recursionClients.Networks, recursionClients.Keys); err != nil {
+		log.Fatalf("Invalid recursion ACL configuration: %v", err)
+	}
+
+	// Setup views selected by client network
+	var viewConfigs []views.Config
+	if err := viper.UnmarshalKey("dns.views", &viewConfigs); err != nil {
+		log.Fatalf("Invalid view configuration: %v", err)
+	}
+	clientViews, err = views.New(viewConfigs, tsigSecrets, database)
+	if err != nil {
+		log.Fatalf("Invalid view configuration: %v", err)
+	}
+
+	// Load the keys zones are signed with
+	var keyFiles []dnssec.KeyFile
+	if err := viper.UnmarshalKey("dns.dnssec.keys", &keyFiles); err != nil {
+		log.Fatalf("Invalid DNSSEC key configuration: %v", err)
+	}
+	signer = dnssec.New(viper.GetDuration("dns.dnssec.signature-validity"), viper.GetDuration("dns.dnssec.zsk-lifetime"), viper.GetDuration("dns.dnssec.rollover-delay"), viper.GetString("dns.dnssec.key-secret"), database)
+	for _, keyFile := range keyFiles {
+		key, err := dnssec.LoadKeyFile(keyFile.Zone, keyFile.File)
+		if err != nil {
+			log.Fatalf("Failed to load DNSSEC key '%s': %v", keyFile.File, err)
+		}
+		signer.AddKey(key)
+	}
+	if err := signer.Load(); err != nil {
+		log.Fatalf("Failed to load DNSSEC keys: %v", err)
+	}
+
+	// Manage keys from the command line instead of serving
+	if pflag.Arg(0) == "keys" {
+		if err := signer.Command(pflag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	signer.Start()
+
+	// Start transferring secondary zones from their primaries
+	var secondaryZones []secondary.Config
+	if err := viper.UnmarshalKey("dns.secondaries", &secondaryZones); err != nil {
+		log.Fatalf("Invalid secondary zone configuration: %v", err)
+	}
+	secondaries, err = secondary.Start(secondaryZones, tsigSecrets, database)
+	if err != nil {
+		log.Fatalf("Invalid secondary zone configuration: %v", err)
+	}
+
+	// Setup upstream resolvers, or resolve from the root servers ourselves
+	var fallback upstream.Resolver
+	switch viper.GetString("dns.recursion-mode") {
+	case "forward":
+		fallback, err = upstream.New(viper.GetStringSlice("dns.upstream"), viper.GetString("dns.upstream-selection"), viper.GetDuration("dns.upstream-timeout"), viper.GetInt("dns.upstream-failures"), viper.GetDuration("dns.upstream-cooldown"))
+	case "iterative":
+		fallback, err = upstream.NewIterative(viper.GetStringSlice("dns.root-hints"), viper.GetInt("dns.nameserver-port"), viper.GetDuration("dns.upstream-timeout"), viper.GetBool("dns.qname-minimisation"))
+	default:
+		err = fmt.Errorf("recursion mode must be one of 'forward' or 'iterative'")
+	}
+	if err != nil {
+		log.Fatalf("Invalid upstream configuration: %v", err)
+	}
+	upstreams = upstream.NewForwarders(fallback, viper.GetString("dns.upstream-selection"), viper.GetDuration("dns.upstream-timeout"), viper.GetInt("dns.upstream-failures"), viper.GetDuration("dns.upstream-cooldown"))
+
+	// Forward domains to their own resolvers, rules from the configuration file take precedence over the API
+	var forwardingRules []upstream.Rule
+	if err := viper.UnmarshalKey("dns.forwarding", &forwardingRules); err != nil {
+		log.Fatalf("Invalid forwarding configuration: %v", err)
+	}
+	for _, rule := range forwardingRules {
+		rule.Configured = true
+		if err := upstreams.Add(rule); err != nil {
+			log.Fatalf("Invalid forwarding configuration: %v", err)
+		}
+	}
+	storedRules, err := db.ListForwardingRules(database)
+	if err != nil {
+		log.Fatalf("Failed to retrieve forwarding rules: %v", err)
+	}
+	for _, rule := range storedRules {
+		if existing, ok := upstreams.Get(rule.Domain); ok && existing.Configured {
+			log.Printf("Ignoring stored forwarding rule for '%s', it is set in the configuration file", rule.Domain)
+		} else if err := upstreams.Add(upstream.Rule{Domain: rule.Domain, Upstream: rule.Upstream}); err != nil {
+			log.Printf("Ignoring stored forwarding rule for '%s': %v", rule.Domain, err)
+		}
+	}
+
+	// Setup the client subnet sent upstream
+	subnets, err = upstream.NewSubnets(viper.GetBool("dns.ecs.enabled"), viper.GetInt("dns.ecs.ipv4-prefix"), viper.GetInt("dns.ecs.ipv6-prefix"), viper.GetString("dns.ecs.override"))
+	if err != nil {
+		log.Fatalf("Invalid client subnet configuration: %v", err)
+	}
+
+	// Load blocklists and keep them up to date
+	blocker, err = blocklist.New(viper.GetString("dns.blocklist.action"), net.ParseIP(viper.GetString("dns.blocklist.sinkhole-ipv4")), net.ParseIP(viper.GetString("dns.blocklist.sinkhole-ipv6")), viper.GetDuration("dns.blocklist.refresh"), database)
+	if err != nil {
+		log.Fatalf("Invalid blocklist configuration: %v", err)
+	}
+	if err := blocker.Load(); err != nil {
+		log.Fatalf("Failed to load blocklists: %v", err)
+	}
+	blocker.Start()
+
+	// Setup upstream response cache
+	responses = cache.New(viper.GetInt("dns.cache-size"))
+
 	// Setup hashing
 	if err := passlib.UseDefaults(passlib.DefaultsLatest); err != nil {
 		log.Fatal("invalid hash configuration")
 	}
 
+	// Setup rate limits for each listener
+	for _, listener := range []string{"tcp", "udp", "tls"} {
+		var limits ratelimit.Config
+		if err := viper.UnmarshalKey("dns.rate-limit."+listener, &limits); err != nil {
+			log.Fatalf("Invalid rate limit configuration for %s: %v", listener, err)
+		}
+
+		// Every other limited response is slipped unless configured otherwise
+		if !viper.IsSet("dns.rate-limit." + listener + ".slip") {
+			limits.Slip = ratelimit.DefaultSlip
+		}
+		limiters[listener] = ratelimit.New(limits)
+	}
+
 	// Check config is valid
-	if viper.GetBool("dns.disable-tcp") && viper.GetBool("dns.disable-udp") { log.Fatalf("Invalid configuration: tcp and/or udp must be enabled, got both as disabled") }
+	if viper.GetBool("dns.disable-tcp") && viper.GetBool("dns.disable-udp") && viper.GetBool("dns.disable-tls") { log.Fatalf("Invalid configuration: tcp, udp, and/or tls must be enabled, got all as disabled") }
+	if size := viper.GetInt("dns.max-udp-size"); size < dns.MinMsgSize || size > dns.MaxMsgSize { log.Fatalf("Invalid configuration: max-udp-size must be between %d and %d, got %d", dns.MinMsgSize, dns.MaxMsgSize, size) }
+	if mode := viper.GetString("dns.any-mode"); mode != authority.AnyHINFO && mode != authority.AnyAll { log.Fatalf("Invalid configuration: any-mode must be one of 'hinfo' or 'all', got '%s'", mode) }
 
 	// Handle TCP connections
 	tcpErr := make(chan error)
 	go func() {
 		if viper.GetBool("dns.disable-tcp") { return }
-		tcp := &dns.Server{Addr: viper.GetString("dns.host") + ":" + viper.GetString("dns.port"), Net: "tcp"}
-		tcp.Handler = &handler{}
+		tcp := &dns.Server{Addr: viper.GetString("dns.host") + ":" + viper.GetString("dns.port"), Net: "tcp", TsigSecret: tsigSecrets, MsgAcceptFunc: authority.AcceptMessage}
+		tcp.Handler = &handler{limiter: limiters["tcp"]}
 
 		if err := tcp.ListenAndServe(); err != nil { tcpErr <- err }
 	}()
@@ -308,12 +861,27 @@ func main() {
 	udpErr := make(chan error)
 	go func() {
 		if viper.GetBool("dns.disable-udp") { return }
-		udp := &dns.Server{Addr: viper.GetString("dns.host") + ":" + viper.GetString("dns.port"), Net: "udp"}
-		udp.Handler = &handler{}
+		udp := &dns.Server{Addr: viper.GetString("dns.host") + ":" + viper.GetString("dns.port"), Net: "udp", TsigSecret: tsigSecrets, MsgAcceptFunc: authority.AcceptMessage}
+		udp.Handler = &handler{limiter: limiters["udp"]}
 
 		if err := udp.ListenAndServe(); err != nil { udpErr <- err }
 	}()
 
+	// Handle DNS over TLS connections
+	tlsErr := make(chan error)
+	go func() {
+		if viper.GetBool("dns.disable-tls") { return }
+
+		// Certificates are reloaded when they change on disk
+		certificates, err := util.NewCertificateLoader(viper.GetString("dns.tls-cert"), viper.GetString("dns.tls-key"))
+		if err != nil { tlsErr <- err; return }
+
+		dot := &dns.Server{Addr: viper.GetString("dns.host") + ":" + viper.GetString("dns.tls-port"), Net: "tcp-tls", TLSConfig: &tls.Config{GetCertificate: certificates.GetCertificate}, TsigSecret: tsigSecrets, MsgAcceptFunc: authority.AcceptMessage}
+		dot.Handler = &handler{limiter: limiters["tls"]}
+
+		if err := dot.ListenAndServe(); err != nil { tlsErr <- err }
+	}()
+
 	// Handle REST API
 	httpErr := make(chan error)
 	go func() {
@@ -330,6 +898,17 @@ func main() {
 		http.Handle("/api/users/logout", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(users.Logout(database)))))
 		http.Handle("/api/roles", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(roles.AllRolesHandler(database)))))
 		http.Handle("/api/roles/", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(roles.SingleRoleHandler("/api/roles/", database)))))
+		http.Handle("/api/zones", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(zones.AllZonesHandler(database)))))
+		http.Handle("/api/zones/", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(zones.SingleZoneHandler("/api/zones/", database)))))
+		http.Handle("/api/views", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(views.ViewsHandler(clientViews, database)))))
+		http.Handle("/api/keys/", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(keys.KeysHandler("/api/keys/", database, signer)))))
+		http.Handle("/dns-query", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(dnsOverHTTPS))))
+		http.Handle("/api/blocklists", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(blocklist.AllBlocklistsHandler(blocker, database)))))
+		http.Handle("/api/blocklists/", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(blocklist.SingleBlocklistHandler("/api/blocklists/", blocker, database)))))
+		http.Handle("/api/forwarders", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(forwarders.AllForwardersHandler(upstreams, database)))))
+		http.Handle("/api/forwarders/", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(forwarders.SingleForwarderHandler("/api/forwarders/", upstreams, database)))))
+		http.Handle("/api/rate-limit", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(ratelimit.StatsHandler(limiters, database)))))
+		http.Handle("/api/cache", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(cache.StatsHandler(responses, database)))))
 
 		// Setup frontend routes
 		if !viper.GetBool("http.disable-frontend") {
@@ -350,7 +929,8 @@ func main() {
 	} else {
 		protocols = "UDP"
 	}
-	log.Printf("DNS server listening on %s:%s with %s...", viper.GetString("dns.host"), viper.GetString("dns.port"), protocols)
+	if !viper.GetBool("dns.disable-udp") || !viper.GetBool("dns.disable-tcp") { log.Printf("DNS server listening on %s:%s with %s...", viper.GetString("dns.host"), viper.GetString("dns.port"), protocols) }
+	if !viper.GetBool("dns.disable-tls") { log.Printf("DNS server listening on %s:%s with TLS...", viper.GetString("dns.host"), viper.GetString("dns.tls-port")) }
 
 	if !viper.GetBool("http.disabled") { log.Printf("HTTP server listening on %s:%s...", viper.GetString("http.host"), viper.GetString("http.port")) }
 
@@ -360,6 +940,8 @@ func main() {
 		log.Fatalf("DNS failed to listen on %s:%s with TCP: %v\n", viper.GetString("dns.host"), viper.GetString("dns.port"), err)
 	case err := <- udpErr:
 		log.Fatalf("DNS failed to listen on %s:%s with UDP: %v\n", viper.GetString("dns.host"), viper.GetString("dns.port"), err)
+	case err := <- tlsErr:
+		log.Fatalf("DNS failed to listen on %s:%s with TLS: %v\n", viper.GetString("dns.host"), viper.GetString("dns.tls-port"), err)
 	case err := <- httpErr:
 		log.Fatalf("API failed to listen on %s:%s: %v\n", viper.GetString("http.host"), viper.GetString("http.port"), err)
 	}
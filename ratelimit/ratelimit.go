@@ -0,0 +1,227 @@
+package ratelimit
+
+import (
+	"github.com/miekg/dns"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often accounts that have not been used for a window are removed
+const sweepInterval = time.Minute
+
+// What to do with a response after rate limiting
+type Action int
+
+const (
+	// Send the response as is
+	Allow Action = iota
+	// Send nothing at all
+	Drop
+	// Send an empty truncated response, so real clients retry over TCP
+	Slip
+)
+
+// How often limited responses are slipped when not configured
+const DefaultSlip = 2
+
+// Rate limits for a single listener, where a limit of 0 disables it
+type Config struct {
+	ResponsesPerSecond int `mapstructure:"responses-per-second"`
+	Window             int `mapstructure:"window"`
+	Slip               int `mapstructure:"slip"`
+	QueriesPerSecond   int `mapstructure:"queries-per-second"`
+	IPv4Prefix         int `mapstructure:"ipv4-prefix"`
+	IPv6Prefix         int `mapstructure:"ipv6-prefix"`
+}
+
+// Counters of what a limiter let through, dropped, and slipped
+type Stats struct {
+	Allowed        uint64 `json:"allowed"`
+	Dropped        uint64 `json:"dropped"`
+	Slipped        uint64 `json:"slipped"`
+	QueriesDropped uint64 `json:"queries-dropped"`
+	Accounts       int    `json:"accounts"`
+}
+
+// Limits identical responses and queries per client prefix in the style of BIND's response rate limiting.
+// Each account earns its rate in credits every second and spends one per response. Once out of credit
+// its responses are limited, and it can go into debt for up to a window of responses before it recovers.
+type Limiter struct {
+	mu        sync.Mutex
+	config    Config
+	responses map[string]*account
+	queries   map[string]*account
+	stats     Stats
+	lastSweep time.Time
+}
+
+// The credit of a single client prefix and response
+type account struct {
+	balance float64
+	updated time.Time
+	limited int
+}
+
+// Create a limiter for a listener
+func New(config Config) *Limiter {
+	if config.Window <= 0 {
+		config.Window = 15
+	}
+	if config.IPv4Prefix <= 0 || config.IPv4Prefix > 32 {
+		config.IPv4Prefix = 24
+	}
+	if config.IPv6Prefix <= 0 || config.IPv6Prefix > 128 {
+		config.IPv6Prefix = 56
+	}
+
+	return &Limiter{
+		config:    config,
+		responses: make(map[string]*account),
+		queries:   make(map[string]*account),
+		lastSweep: time.Now(),
+	}
+}
+
+// Check if a client may send another query
+func (l *Limiter) AllowQuery(ip net.IP) bool {
+	if l.config.QueriesPerSecond <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prefix := l.prefix(ip)
+	a := l.spend(l.queries, prefix, l.config.QueriesPerSecond)
+	if a.balance >= 0 {
+		a.limited = 0
+		return true
+	}
+
+	if a.limited == 0 {
+		log.Printf("Limiting queries from %s to %d per second", prefix, l.config.QueriesPerSecond)
+	}
+	a.limited++
+	l.stats.QueriesDropped++
+	return false
+}
+
+// Decide what to do with a response to a client
+func (l *Limiter) Response(ip net.IP, r *dns.Msg) Action {
+	if l.config.ResponsesPerSecond <= 0 {
+		return Allow
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prefix := l.prefix(ip)
+	a := l.spend(l.responses, prefix+" "+class(r), l.config.ResponsesPerSecond)
+	if a.balance >= 0 {
+		a.limited = 0
+		l.stats.Allowed++
+		return Allow
+	}
+
+	if a.limited == 0 {
+		log.Printf("Limiting responses to %s for %s to %d per second", prefix, class(r), l.config.ResponsesPerSecond)
+	}
+	a.limited++
+
+	// Every slip'th limited response is sent truncated instead of dropped
+	if l.config.Slip > 0 && a.limited%l.config.Slip == 0 {
+		l.stats.Slipped++
+		return Slip
+	}
+	l.stats.Dropped++
+	return Drop
+}
+
+// Get the counters of the limiter
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.stats
+	stats.Accounts = len(l.responses) + len(l.queries)
+	return stats
+}
+
+// Spend a credit from an account, creating it with a full balance if new
+func (l *Limiter) spend(accounts map[string]*account, key string, rate int) *account {
+	now := time.Now()
+	l.sweep(now)
+
+	a, ok := accounts[key]
+	if !ok {
+		a = &account{balance: float64(rate), updated: now}
+		accounts[key] = a
+	}
+
+	// Credit is earned continuously, but never more than a second's worth
+	a.balance += now.Sub(a.updated).Seconds() * float64(rate)
+	if a.balance > float64(rate) {
+		a.balance = float64(rate)
+	}
+	a.updated = now
+
+	// Debt is capped so abusive clients recover after a quiet window
+	a.balance--
+	if debt := -float64(rate * l.config.Window); a.balance < debt {
+		a.balance = debt
+	}
+	return a
+}
+
+// Remove accounts that have been quiet long enough to have recovered
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	idle := time.Duration(l.config.Window) * time.Second
+	for _, accounts := range []map[string]*account{l.responses, l.queries} {
+		for key, a := range accounts {
+			if now.Sub(a.updated) > idle {
+				delete(accounts, key)
+			}
+		}
+	}
+}
+
+// Get the prefix a client address is accounted under
+func (l *Limiter) prefix(ip net.IP) string {
+	if ip == nil {
+		return "unknown"
+	} else if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(l.config.IPv4Prefix, 32)).String() + "/" + strconv.Itoa(l.config.IPv4Prefix)
+	}
+	return ip.Mask(net.CIDRMask(l.config.IPv6Prefix, 128)).String() + "/" + strconv.Itoa(l.config.IPv6Prefix)
+}
+
+// Get what makes responses identical for rate limiting. Answers are identified by their
+// question, negative answers by the zone they came from so random names share an account.
+func class(r *dns.Msg) string {
+	var question string
+	if len(r.Question) != 0 {
+		question = strings.ToLower(r.Question[0].Name) + " " + dns.TypeToString[r.Question[0].Qtype]
+	}
+
+	switch {
+	case r.Rcode == dns.RcodeNameError || (r.Rcode == dns.RcodeSuccess && len(r.Answer) == 0):
+		for _, rr := range r.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return dns.RcodeToString[r.Rcode] + " " + strings.ToLower(soa.Hdr.Name)
+			}
+		}
+		return dns.RcodeToString[r.Rcode] + " " + question
+	case r.Rcode != dns.RcodeSuccess:
+		return dns.RcodeToString[r.Rcode]
+	}
+	return question
+}
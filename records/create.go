@@ -2,6 +2,7 @@ package records
 
 import (
 	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/authority"
 	"github.com/akrantz01/krantz.dev/dns/db"
 	"github.com/akrantz01/krantz.dev/dns/util"
 	bolt "go.etcd.io/bbolt"
@@ -42,8 +43,14 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 	}); err != "" {
 		util.Responses.Error(w, http.StatusBadRequest, err)
 		return
+	} else if !util.ValidRecordName(body["name"].(string)) {
+		util.Responses.Error(w, http.StatusBadRequest, "invalid record name")
+		return
 	}
 
+	// Names are matched case insensitively, so they are stored lowercase
+	body["name"] = strings.ToLower(body["name"].(string))
+
 	// Verify JWT in headers
 	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
 	if err != nil {
@@ -67,30 +74,58 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		return
 	}
 
-	// Parse out body by type
+	// Records of secondary zones are managed by their primary
+	if zone, err := db.FindZone(body["name"].(string), database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to find zone: "+err.Error())
+		return
+	} else if zone != nil && zone.Secondary() {
+		util.Responses.Error(w, http.StatusForbidden, "records in secondary zone '"+zone.Name+"' are read-only")
+		return
+	}
+
+	// Records are managed in the default view unless another is given
+	view := r.URL.Query().Get("view")
+	if !db.ViewExists(view, database) {
+		util.Responses.Error(w, http.StatusBadRequest, "view '"+view+"' does not exist")
+		return
+	}
+	setter := db.Set.In(view)
+
+	// Records without a TTL use the default
+	var ttl uint32
+	if err, valid := util.ValidateBody(body, []string{"ttl"}, map[string]map[string]string{"ttl": {"type": "uint32", "required": "false"}}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	} else if valid["ttl"] {
+		ttl = uint32(body["ttl"].(float64))
+	}
+
+	// Parse out body by type, adding a new member to the record set
+	var id string
+	var writeErr error
 	switch strings.ToUpper(body["type"].(string)) {
 	case "A":
 		if err, _ := util.ValidateBody(body, []string{"host"}, map[string]map[string]string{"host": {"required": "true", "type": "ipv4"}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.A(body["name"].(string), body["host"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.A(body["name"].(string), "", ttl, body["host"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "AAAA":
 		if err, _ := util.ValidateBody(body, []string{"host"}, map[string]map[string]string{"host": {"required": "true", "type": "ipv6"}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.AAAA(body["name"].(string), body["host"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.AAAA(body["name"].(string), "", ttl, body["host"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "CNAME":
 		if err, _ := util.ValidateBody(body, []string{"target"}, map[string]map[string]string{"target": {"required": "true", "type": "string"}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.CNAME(body["name"].(string), body["target"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.CNAME(body["name"].(string), "", ttl, body["target"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "MX":
@@ -100,8 +135,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.MX(body["name"].(string), uint16(body["priority"].(float64)), body["host"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusBadRequest, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.MX(body["name"].(string), "", ttl, uint16(body["priority"].(float64)), body["host"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "LOC":
@@ -122,8 +157,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.LOC(body["name"].(string), uint8(body["version"].(float64)), uint8(body["size"].(float64)), uint8(body["horizontal-precision"].(float64)), uint8(body["vertical-precision"].(float64)), uint32(body["altitude"].(float64)), uint8(body["lat-degrees"].(float64)), uint8(body["lat-minutes"].(float64)), uint8(body["lat-seconds"].(float64)), body["lat-direction"].(string), uint8(body["long-degrees"].(float64)), uint8(body["long-minutes"].(float64)), uint8(body["long-seconds"].(float64)), body["long-direction"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.LOC(body["name"].(string), "", ttl, uint8(body["version"].(float64)), uint8(body["size"].(float64)), uint8(body["horizontal-precision"].(float64)), uint8(body["vertical-precision"].(float64)), uint32(body["altitude"].(float64)), uint8(body["lat-degrees"].(float64)), uint8(body["lat-minutes"].(float64)), uint8(body["lat-seconds"].(float64)), body["lat-direction"].(string), uint8(body["long-degrees"].(float64)), uint8(body["long-minutes"].(float64)), uint8(body["long-seconds"].(float64)), body["long-direction"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "SRV":
@@ -135,8 +170,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.SRV(body["name"].(string), uint16(body["priority"].(float64)), uint16(body["weight"].(float64)), uint16(body["port"].(float64)), body["target"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.SRV(body["name"].(string), "", ttl, uint16(body["priority"].(float64)), uint16(body["weight"].(float64)), uint16(body["port"].(float64)), body["target"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "SPF":
@@ -145,8 +180,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 			return
 		}
 		text, _ := util.ConvertArrayToString(body["text"].([]interface{}))
-		if err := db.Set.SPF(body["name"].(string), text); err != nil {
-			util.Responses.Error(w, http.StatusBadRequest, "failed to write record to database: "+err.Error())
+		if id, writeErr = setter.SPF(body["name"].(string), "", ttl, text); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "TXT":
@@ -155,16 +190,16 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 			return
 		}
 		text, _ := util.ConvertArrayToString(body["text"].([]interface{}))
-		if err := db.Set.TXT(body["name"].(string), text); err != nil {
-			util.Responses.Error(w, http.StatusBadRequest, "failed to write record to database: "+err.Error())
+		if id, writeErr = setter.TXT(body["name"].(string), "", ttl, text); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "NS":
 		if err, _ := util.ValidateBody(body, []string{"nameserver"}, map[string]map[string]string{"nameserver": {"type": "string", "required": "true"}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.NS(body["name"].(string), body["nameserver"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.NS(body["name"].(string), "", ttl, body["nameserver"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "CAA":
@@ -174,16 +209,16 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.CAA(body["name"].(string), body["tag"].(string), body["content"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.CAA(body["name"].(string), "", ttl, body["tag"].(string), body["content"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "PTR":
 		if err, _ := util.ValidateBody(body, []string{"domain"}, map[string]map[string]string{"domain": {"type": "string", "required": "true"}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.PTR(body["name"].(string), body["domain"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.PTR(body["name"].(string), "", ttl, body["domain"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "CERT":
@@ -195,8 +230,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.CERT(body["name"].(string), uint16(body["c-type"].(float64)), uint16(body["key-tag"].(float64)), uint8(body["algorithm"].(float64)), body["certificate"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.CERT(body["name"].(string), "", ttl, uint16(body["c-type"].(float64)), uint16(body["key-tag"].(float64)), uint8(body["algorithm"].(float64)), body["certificate"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "DNSKEY":
@@ -208,8 +243,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.DNSKEY(body["name"].(string), uint16(body["flags"].(float64)), uint8(body["protocol"].(float64)), uint8(body["algorithm"].(float64)), body["public-key"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.DNSKEY(body["name"].(string), "", ttl, uint16(body["flags"].(float64)), uint8(body["protocol"].(float64)), uint8(body["algorithm"].(float64)), body["public-key"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "DS":
@@ -221,8 +256,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.DS(body["name"].(string), uint16(body["key-tag"].(float64)), uint8(body["algorithm"].(float64)), uint8(body["digest-type"].(float64)), body["digest"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.DS(body["name"].(string), "", ttl, uint16(body["key-tag"].(float64)), uint8(body["algorithm"].(float64)), uint8(body["digest-type"].(float64)), body["digest"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "NAPTR":
@@ -236,8 +271,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.NAPTR(body["name"].(string), uint16(body["order"].(float64)), uint16(body["preference"].(float64)), body["flags"].(string), body["service"].(string), body["regexp"].(string), body["replacement"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.NAPTR(body["name"].(string), "", ttl, uint16(body["order"].(float64)), uint16(body["preference"].(float64)), body["flags"].(string), body["service"].(string), body["regexp"].(string), body["replacement"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "SMIMEA":
@@ -249,8 +284,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.SMIMEA(body["name"].(string), uint8(body["usage"].(float64)), uint8(body["selector"].(float64)), uint8(body["matching-type"].(float64)), body["certificate"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.SMIMEA(body["name"].(string), "", ttl, uint8(body["usage"].(float64)), uint8(body["selector"].(float64)), uint8(body["matching-type"].(float64)), body["certificate"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "SSHFP":
@@ -261,8 +296,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.SSHFP(body["name"].(string), uint8(body["algorithm"].(float64)), uint8(body["s-type"].(float64)), body["fingerprint"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.SSHFP(body["name"].(string), "", ttl, uint8(body["algorithm"].(float64)), uint8(body["s-type"].(float64)), body["fingerprint"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "TLSA":
@@ -274,8 +309,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.TLSA(body["name"].(string), uint8(body["usage"].(float64)), uint8(body["selector"].(float64)), uint8(body["matching-type"].(float64)), body["certificate"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.TLSA(body["name"].(string), "", ttl, uint8(body["usage"].(float64)), uint8(body["selector"].(float64)), uint8(body["matching-type"].(float64)), body["certificate"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	case "URI":
@@ -286,8 +321,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.URI(body["name"].(string), uint16(body["priority"].(float64)), uint16(body["weight"].(float64)), body["target"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if id, writeErr = setter.URI(body["name"].(string), "", ttl, uint16(body["priority"].(float64)), uint16(body["weight"].(float64)), body["target"].(string)); writeErr != nil {
+			writeError(w, writeErr)
 			return
 		}
 	default:
@@ -295,5 +330,19 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		return
 	}
 
-	util.Responses.Success(w)
+	// Only the default view is journaled and transferred
+	if view == "" {
+		authority.JournalRecord(body["name"].(string), database)
+	}
+
+	util.Responses.SuccessWithData(w, map[string]string{"id": id})
+}
+
+// Respond with the reason a record could not be written
+func writeError(w http.ResponseWriter, err error) {
+	if err == db.ErrRecordExists {
+		util.Responses.Error(w, http.StatusBadRequest, "a record with the same data already exists")
+		return
+	}
+	util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 }
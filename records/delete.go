@@ -1,6 +1,7 @@
 package records
 
 import (
+	"github.com/akrantz01/krantz.dev/dns/authority"
 	"github.com/akrantz01/krantz.dev/dns/db"
 	"github.com/akrantz01/krantz.dev/dns/util"
 	bolt "go.etcd.io/bbolt"
@@ -48,6 +49,11 @@ func deleteRecord(w http.ResponseWriter, r *http.Request, path string, database
 	// Accounts for extra dot and all lowercase in DNS query
 	record := strings.ToLower(r.URL.Path[len(path):])
 
+	if !util.ValidRecordName(record) {
+		util.Responses.Error(w, http.StatusBadRequest, "invalid record name")
+		return
+	}
+
 	// Check if allowed
 	if allowed, err := db.EvaluateRole(user.Role, record, database); err != nil {
 		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
@@ -57,45 +63,65 @@ func deleteRecord(w http.ResponseWriter, r *http.Request, path string, database
 		return
 	}
 
+	// Records of secondary zones are managed by their primary
+	if zone, err := db.FindZone(record, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to find zone: "+err.Error())
+		return
+	} else if zone != nil && zone.Secondary() {
+		util.Responses.Error(w, http.StatusForbidden, "records in secondary zone '"+zone.Name+"' are read-only")
+		return
+	}
+
+	// Records are managed in the default view unless another is given
+	view := r.URL.Query().Get("view")
+	if !db.ViewExists(view, database) {
+		util.Responses.Error(w, http.StatusBadRequest, "view '"+view+"' does not exist")
+		return
+	}
+	deleter := db.Delete.In(view)
+
+	// Remove a single member of the set if an id is given
+	id := r.URL.Query().Get("id")
+
 	switch r.URL.Query().Get("type") {
 	case "A":
-		err = db.Delete.A(record)
+		err = deleter.A(record, id)
 	case "AAAA":
-		err = db.Delete.AAAA(record)
+		err = deleter.AAAA(record, id)
 	case "CNAME":
-		err = db.Delete.CNAME(record)
+		err = deleter.CNAME(record, id)
 	case "MX":
-		err = db.Delete.MX(record)
+		err = deleter.MX(record, id)
 	case "LOC":
-		err = db.Delete.LOC(record)
+		err = deleter.LOC(record, id)
 	case "SRV":
-		err = db.Delete.SRV(record)
+		err = deleter.SRV(record, id)
 	case "SPF":
-		err = db.Delete.SPF(record)
+		err = deleter.SPF(record, id)
 	case "TXT":
-		err = db.Delete.TXT(record)
+		err = deleter.TXT(record, id)
 	case "NS":
-		err = db.Delete.NS(record)
+		err = deleter.NS(record, id)
 	case "CAA":
-		err = db.Delete.CAA(record)
+		err = deleter.CAA(record, id)
 	case "PTR":
-		err = db.Delete.PTR(record)
+		err = deleter.PTR(record, id)
 	case "CERT":
-		err = db.Delete.CERT(record)
+		err = deleter.CERT(record, id)
 	case "DNSKEY":
-		err = db.Delete.DNSKEY(record)
+		err = deleter.DNSKEY(record, id)
 	case "DS":
-		err = db.Delete.DS(record)
+		err = deleter.DS(record, id)
 	case "NAPTR":
-		err = db.Delete.NAPTR(record)
+		err = deleter.NAPTR(record, id)
 	case "SMIMEA":
-		err = db.Delete.SMIMEA(record)
+		err = deleter.SMIMEA(record, id)
 	case "SSHFP":
-		err = db.Delete.SSHFP(record)
+		err = deleter.SSHFP(record, id)
 	case "TLSA":
-		err = db.Delete.TLSA(record)
+		err = deleter.TLSA(record, id)
 	case "URI":
-		err = db.Delete.URI(record)
+		err = deleter.URI(record, id)
 	default:
 		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI")
 		return
@@ -105,5 +131,11 @@ func deleteRecord(w http.ResponseWriter, r *http.Request, path string, database
 		util.Responses.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
+
+	// Only the default view is journaled and transferred
+	if view == "" {
+		authority.JournalRecord(record, database)
+	}
+
 	util.Responses.Success(w)
 }
@@ -5,7 +5,6 @@ import (
 	"github.com/akrantz01/krantz.dev/dns/util"
 	bolt "go.etcd.io/bbolt"
 	"net/http"
-	"strings"
 )
 
 // Handle the listing of all records
@@ -25,18 +24,28 @@ func list(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		return
 	}
 
-	// List all records of a type if query parameter given
-	if len(r.URL.RawQuery) != 0 {
-		if _, ok := r.URL.Query()["type"]; !ok {
+	// Records are listed from the default view unless another is given
+	view := r.URL.Query().Get("view")
+	if !db.ViewExists(view, database) {
+		util.Responses.Error(w, http.StatusBadRequest, "view '"+view+"' does not exist")
+		return
+	}
+
+	// List all records of a type if query parameters other than the view are given
+	filters := r.URL.Query()
+	delete(filters, "view")
+	if len(filters) != 0 {
+		if _, ok := filters["type"]; !ok {
 			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' is required for type filtering")
 			return
 		}
 
 		var rawRecords []map[string]string
-		for _, record := range r.URL.Query()["type"] {
+		for _, record := range filters["type"] {
 			if err := database.View(func(tx *bolt.Tx) error {
-				return tx.Bucket([]byte(record)).ForEach(func(k, v []byte) error {
-					rawRecords = append(rawRecords, map[string]string{"name": strings.Split(string(k), "*")[0], "type": record})
+				return db.Bucket(tx, view, record).ForEach(func(k, v []byte) error {
+					name, _, _ := db.SplitKey(string(k))
+					rawRecords = append(rawRecords, map[string]string{"name": name, "type": record})
 					return nil
 				})
 			}); err != nil {
@@ -67,8 +76,9 @@ func list(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 	var rawRecords []map[string]string
 	for _, record := range []string{"A", "AAAA", "CNAME", "MX", "LOC", "SRV", "SPF", "TXT", "NS", "CAA", "PTR", "CERT", "DNSKEY", "DS", "NAPTR", "SMIMEA", "SSHFP", "TLSA", "URI"} {
 		if err := database.View(func(tx *bolt.Tx) error {
-			return tx.Bucket([]byte(record)).ForEach(func(k, v []byte) error {
-				rawRecords = append(rawRecords, map[string]string{"name": strings.Split(string(k), "*")[0], "type": record})
+			return db.Bucket(tx, view, record).ForEach(func(k, v []byte) error {
+				name, _, _ := db.SplitKey(string(k))
+				rawRecords = append(rawRecords, map[string]string{"name": name, "type": record})
 				return nil
 			})
 		}); err != nil {
@@ -1,7 +1,6 @@
 package records
 
 import (
-	"fmt"
 	"github.com/akrantz01/krantz.dev/dns/db"
 	"github.com/akrantz01/krantz.dev/dns/util"
 	bolt "go.etcd.io/bbolt"
@@ -39,56 +38,63 @@ func read(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB
 		return
 	}
 
+	// Records are managed in the default view unless another is given
+	view := r.URL.Query().Get("view")
+	if !db.ViewExists(view, database) {
+		util.Responses.Error(w, http.StatusBadRequest, "view '"+view+"' does not exist")
+		return
+	}
+	getter := db.Get.In(view)
+
 	// Accounts for extra dot and all lowercase in DNS request
 	record := strings.ToLower(r.URL.Path[len(path):] + ".")
-	var response db.Record
+	var response interface{}
 
 	switch r.URL.Query().Get("type") {
 	case "A":
-		response = db.Get.A(record)
+		response = getter.A(record)
 	case "AAAA":
-		response = db.Get.AAAA(record)
+		response = getter.AAAA(record)
 	case "CNAME":
-		response = db.Get.CNAME(record)
+		response = getter.CNAME(record)
 	case "MX":
-		response = db.Get.MX(record)
+		response = getter.MX(record)
 	case "LOC":
-		response = db.Get.LOC(record)
+		response = getter.LOC(record)
 	case "SRV":
-		response = db.Get.SRV(record)
+		response = getter.SRV(record)
 	case "SPF":
-		response = db.Get.SPF(record)
+		response = getter.SPF(record)
 	case "TXT":
-		response = db.Get.TXT(record)
+		response = getter.TXT(record)
 	case "NS":
-		response = db.Get.NS(record)
+		response = getter.NS(record)
 	case "CAA":
-		response = db.Get.CERT(record)
+		response = getter.CERT(record)
 	case "PTR":
-		response = db.Get.PTR(record)
+		response = getter.PTR(record)
 	case "CERT":
-		response = db.Get.CERT(record)
+		response = getter.CERT(record)
 	case "DNSKEY":
-		response = db.Get.DNSKEY(record)
+		response = getter.DNSKEY(record)
 	case "DS":
-		response = db.Get.DS(record)
+		response = getter.DS(record)
 	case "NAPTR":
-		response = db.Get.NAPTR(record)
+		response = getter.NAPTR(record)
 	case "SMIMEA":
-		response = db.Get.SMIMEA(record)
+		response = getter.SMIMEA(record)
 	case "SSHFP":
-		response = db.Get.SSHFP(record)
+		response = getter.SSHFP(record)
 	case "TLSA":
-		response = db.Get.TLSA(record)
+		response = getter.TLSA(record)
 	case "URI":
-		response = db.Get.URI(record)
+		response = getter.URI(record)
 	default:
 		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI")
 		return
 	}
 
-	// I know this is a hack, but its the best thing I could think of
-	if fmt.Sprintf("%v", response) == "<nil>" {
+	if util.RecordDoesNotExist(response) {
 		util.Responses.Error(w, http.StatusBadRequest, "record does not exist")
 		return
 	}
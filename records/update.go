@@ -2,6 +2,7 @@ package records
 
 import (
 	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/authority"
 	"github.com/akrantz01/krantz.dev/dns/db"
 	"github.com/akrantz01/krantz.dev/dns/util"
 	bolt "go.etcd.io/bbolt"
@@ -50,6 +51,11 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 
 	recordName := strings.ToLower(r.URL.Path[len(path):])
 
+	if !util.ValidRecordName(recordName) {
+		util.Responses.Error(w, http.StatusBadRequest, "invalid record name")
+		return
+	}
+
 	// Check if allowed
 	if allowed, err := db.EvaluateRole(user.Role, recordName, database); err != nil {
 		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
@@ -59,6 +65,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		return
 	}
 
+	// Records of secondary zones are managed by their primary
+	if zone, err := db.FindZone(recordName, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to find zone: "+err.Error())
+		return
+	} else if zone != nil && zone.Secondary() {
+		util.Responses.Error(w, http.StatusForbidden, "records in secondary zone '"+zone.Name+"' are read-only")
+		return
+	}
+
+	// Records are managed in the default view unless another is given
+	view := r.URL.Query().Get("view")
+	if !db.ViewExists(view, database) {
+		util.Responses.Error(w, http.StatusBadRequest, "view '"+view+"' does not exist")
+		return
+	}
+	getter, setter := db.Get.In(view), db.Set.In(view)
+
 	// Validate body by decoding json, checking fields exists, and checking field type
 	var body map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -69,15 +92,40 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		return
 	}
 
+	// Get the member of the record set to update
+	id := ""
+	if err, valid := util.ValidateBody(body, []string{"id"}, map[string]map[string]string{"id": {"type": "string", "required": "false"}}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	} else if valid["id"] {
+		id = body["id"].(string)
+	}
+
+	// Get the new TTL if it is being changed
+	var ttl uint32
+	ttlChanged := false
+	if err, valid := util.ValidateBody(body, []string{"ttl"}, map[string]map[string]string{"ttl": {"type": "uint32", "required": "false"}}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	} else if valid["ttl"] {
+		ttl = uint32(body["ttl"].(float64))
+		ttlChanged = true
+	}
+
 	// Parse out body by type
 	switch strings.ToUpper(body["type"].(string)) {
 	case "A":
-		// Get original record from database
-		record := db.Get.A(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.A(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"host"}, map[string]map[string]string{"host": {"type": "ipv4", "required": "false"}})
@@ -92,18 +140,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to the database
-		if err := db.Set.A(recordName, record.Address.String()); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.A(recordName, record.ID, record.TTL, record.Address.String()); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "AAAA":
-		// Get original record from database
-		record := db.Get.AAAA(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.AAAA(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"host"}, map[string]map[string]string{"host": {"type": "ipv6", "required": "false"}})
@@ -118,18 +171,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to the database
-		if err := db.Set.AAAA(recordName, record.Address.String()); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.AAAA(recordName, record.ID, record.TTL, record.Address.String()); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "CNAME":
-		// Get original record from database
-		record := db.Get.CNAME(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.CNAME(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"target"}, map[string]map[string]string{"target": {"type": "string", "required": "false"}})
@@ -144,18 +202,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to the database
-		if err := db.Set.CNAME(recordName, record.Target); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.CNAME(recordName, record.ID, record.TTL, record.Target); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "MX":
-		// Get original record from database
-		record := db.Get.MX(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.MX(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"host", "priority"}, map[string]map[string]string{"host": {"type": "string", "required": "false"}, "priority": {"type": "uint16", "required": "false"}})
@@ -173,18 +236,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to the database
-		if err := db.Set.MX(recordName, record.Priority, record.Host); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.MX(recordName, record.ID, record.TTL, record.Priority, record.Host); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "LOC":
-		// Get original record from database
-		record := db.Get.LOC(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.LOC(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"version", "size", "horizontal-precision", "vertical-precision", "altitude", "lat-degrees", "lat-minutes", "lat-seconds", "lat-direction", "long-degrees", "long-minutes", "long-seconds", "long-direction"}, map[string]map[string]string{
@@ -249,18 +317,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.LOC(recordName, record.Version, record.Size, record.HorizontalPrecision, record.VerticalPrecision, record.Altitude, record.LatDegrees, record.LatMinutes, record.LatSeconds, record.LatDirection, record.LongDegrees, record.LongMinutes, record.LongSeconds, record.LongDirection); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.LOC(recordName, record.ID, record.TTL, record.Version, record.Size, record.HorizontalPrecision, record.VerticalPrecision, record.Altitude, record.LatDegrees, record.LatMinutes, record.LatSeconds, record.LatDirection, record.LongDegrees, record.LongMinutes, record.LongSeconds, record.LongDirection); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "SRV":
-		// Get original record from database
-		record := db.Get.SRV(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.SRV(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"priority", "weight", "port", "target"}, map[string]map[string]string{
@@ -289,18 +362,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.SRV(recordName, record.Priority, record.Weight, record.Port, record.Target); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.SRV(recordName, record.ID, record.TTL, record.Priority, record.Weight, record.Port, record.Target); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "SPF":
-		// Get original record from database
-		record := db.Get.SPF(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.SPF(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "false"}})
@@ -316,18 +394,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.SPF(recordName, record.Text); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.SPF(recordName, record.ID, record.TTL, record.Text); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "TXT":
-		// Get original record from database
-		record := db.Get.TXT(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.TXT(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "false"}})
@@ -343,18 +426,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.TXT(recordName, record.Text); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.TXT(recordName, record.ID, record.TTL, record.Text); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "NS":
-		// Get original record from database
-		record := db.Get.NS(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.NS(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"nameserver"}, map[string]map[string]string{"nameserver": {"type": "string", "required": "false"}})
@@ -369,18 +457,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.NS(recordName, record.Nameserver); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+ err.Error())
+		if _, err := setter.NS(recordName, record.ID, record.TTL, record.Nameserver); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "CAA":
-		// Get original record from database
-		record := db.Get.CAA(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.CAA(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"tag", "content"}, map[string]map[string]string{"tag": {"type": "string", "required": "false"}, "content": {"type": "string", "required": "false"}})
@@ -397,18 +490,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.CAA(recordName, record.Tag, record.Content); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+ err.Error())
+		if _, err := setter.CAA(recordName, record.ID, record.TTL, record.Tag, record.Content); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "PTR":
-		// Get original record from database
-		record := db.Get.PTR(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.PTR(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"domain"}, map[string]map[string]string{"domain": {"type": "string", "required": "false"}})
@@ -422,18 +520,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.PTR(recordName, record.Domain); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+ err.Error())
+		if _, err := setter.PTR(recordName, record.ID, record.TTL, record.Domain); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "CERT":
-		// Get original record from database
-		record := db.Get.CERT(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.CERT(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"c-type", "key-tag", "algorithm", "certificate"}, map[string]map[string]string{
@@ -461,18 +564,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.CERT(recordName, record.Type, record.KeyTag, record.Algorithm, record.Certificate); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.CERT(recordName, record.ID, record.TTL, record.Type, record.KeyTag, record.Algorithm, record.Certificate); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "DNSKEY":
-		// Get original record from database
-		record := db.Get.DNSKEY(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.DNSKEY(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"flags", "protocol", "algorithm", "public-key"}, map[string]map[string]string{
@@ -500,18 +608,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.DNSKEY(recordName, record.Flags, record.Protocol, record.Algorithm, record.PublicKey); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.DNSKEY(recordName, record.ID, record.TTL, record.Flags, record.Protocol, record.Algorithm, record.PublicKey); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "DS":
-		// Get original record from database
-		record := db.Get.DS(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.DS(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"key-tag", "algorithm", "digest-type", "digest"}, map[string]map[string]string{
@@ -539,18 +652,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.DS(recordName, record.KeyTag, record.Algorithm, record.DigestType, record.Digest); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.DS(recordName, record.ID, record.TTL, record.KeyTag, record.Algorithm, record.DigestType, record.Digest); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "NAPTR":
-		// Get original record from database
-		record := db.Get.NAPTR(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.NAPTR(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"order", "preference", "flags", "service", "regexp", "replacement"}, map[string]map[string]string{
@@ -586,18 +704,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.NAPTR(recordName, record.Order, record.Preference, record.Flags, record.Service, record.Regexp, record.Replacement); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.NAPTR(recordName, record.ID, record.TTL, record.Order, record.Preference, record.Flags, record.Service, record.Regexp, record.Replacement); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "SMIMEA":
-		// Get original record from database
-		record := db.Get.SMIMEA(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.SMIMEA(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"usage", "selector", "matching-type", "certificate"}, map[string]map[string]string{
@@ -625,18 +748,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.SMIMEA(recordName, record.Usage, record.Selector, record.MatchingType, record.Certificate); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.SMIMEA(recordName, record.ID, record.TTL, record.Usage, record.Selector, record.MatchingType, record.Certificate); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "SSHFP":
-		// Get original record from database
-		record := db.Get.SSHFP(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.SSHFP(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"algorithm", "s-type", "fingerprint"}, map[string]map[string]string{
@@ -660,18 +788,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.SSHFP(recordName, record.Algorithm, record.Type, record.Fingerprint); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.SSHFP(recordName, record.ID, record.TTL, record.Algorithm, record.Type, record.Fingerprint); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "TLSA":
-		// Get original record from database
-		record := db.Get.TLSA(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.TLSA(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"usage", "selector", "matching-type", "certificate"}, map[string]map[string]string{
@@ -699,18 +832,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.TLSA(recordName, record.Usage, record.Selector, record.MatchingType, record.Certificate); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.TLSA(recordName, record.ID, record.TTL, record.Usage, record.Selector, record.MatchingType, record.Certificate); err != nil {
+			writeError(w, err)
 			return
 		}
 
 	case "URI":
-		// Get original record from database
-		record := db.Get.URI(recordName + ".")
-		if util.RecordDoesNotExist(record) {
-			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		// Get original member of the record set from database
+		set := getter.URI(recordName + ".")
+		i, err := selectMember(len(set), id, func(i int) string { return set[i].ID })
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
+		record := set[i]
+		if ttlChanged {
+			record.TTL = ttl
+		}
 
 		// Get valid values in body
 		err, valid := util.ValidateBody(body, []string{"priority", "weight", "target"}, map[string]map[string]string{
@@ -734,8 +872,8 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.URI(recordName, record.Priority, record.Weight, record.Target); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		if _, err := setter.URI(recordName, record.ID, record.TTL, record.Priority, record.Weight, record.Target); err != nil {
+			writeError(w, err)
 			return
 		}
 	default:
@@ -743,5 +881,28 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		return
 	}
 
+	// Only the default view is journaled and transferred
+	if view == "" {
+		authority.JournalRecord(recordName, database)
+	}
+
 	util.Responses.Success(w)
 }
+
+// Find the member of a record set to update, the id can only be omitted if there is a single member
+func selectMember(size int, id string, idAt func(int) string) (int, string) {
+	if size == 0 {
+		return 0, "specified record does not exist"
+	} else if id == "" && size > 1 {
+		return 0, "field 'id' is required for records with multiple values"
+	} else if id == "" {
+		return 0, ""
+	}
+
+	for i := 0; i < size; i++ {
+		if idAt(i) == id {
+			return i, ""
+		}
+	}
+	return 0, "specified record does not exist"
+}
@@ -0,0 +1,276 @@
+package secondary
+
+import (
+	"fmt"
+	"github.com/akrantz01/krantz.dev/dns/authority"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Time to wait before retrying a zone that has never been transferred
+const initialRetry = time.Minute
+
+// A zone to transfer from a primary server
+type Config struct {
+	Zone    string `mapstructure:"zone"`
+	Primary string `mapstructure:"primary"`
+	Key     string `mapstructure:"tsig-key"`
+}
+
+// Keeps secondary zones up to date with their primaries
+type Manager struct {
+	mu      sync.Mutex
+	zones   map[string]*zone
+	secrets map[string]string
+	db      *bolt.DB
+}
+
+// The state of a single secondary zone
+type zone struct {
+	config  Config
+	notify  chan struct{}
+	expired bool
+}
+
+// Start refreshing all secondary zones in the background
+func Start(configs []Config, secrets map[string]string, database *bolt.DB) (*Manager, error) {
+	m := &Manager{zones: make(map[string]*zone), secrets: secrets, db: database}
+
+	for _, config := range configs {
+		if config.Zone == "" || config.Primary == "" {
+			return nil, fmt.Errorf("secondary zones require a zone and primary")
+		} else if _, ok := secrets[dns.Fqdn(strings.ToLower(config.Key))]; config.Key != "" && !ok {
+			return nil, fmt.Errorf("unknown TSIG key '%s' for secondary zone '%s'", config.Key, config.Zone)
+		}
+
+		z := &zone{config: config, notify: make(chan struct{}, 1)}
+		m.zones[db.NormalizeZone(config.Zone)] = z
+		go m.run(z)
+	}
+
+	return m, nil
+}
+
+// Trigger a refresh of a zone after a NOTIFY, returning false if the sender is not its primary
+func (m *Manager) Notify(name string, w dns.ResponseWriter, r *dns.Msg) bool {
+	z, ok := m.zones[db.NormalizeZone(name)]
+	if !ok {
+		return false
+	}
+
+	// Notifications must come from the primary or be signed with its key
+	if tsig := r.IsTsig(); z.config.Key != "" && tsig != nil && w.TsigStatus() == nil {
+		if strings.ToLower(tsig.Hdr.Name) != dns.Fqdn(strings.ToLower(z.config.Key)) {
+			return false
+		}
+	} else if !fromPrimary(w.RemoteAddr(), z.config.Primary) {
+		return false
+	}
+
+	select {
+	case z.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Check if a zone has gone longer than its expire timer without reaching the primary
+func (m *Manager) Expired(name string) bool {
+	z, ok := m.zones[db.NormalizeZone(name)]
+	if !ok {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return z.expired
+}
+
+// Refresh a zone on its SOA timers until the server stops
+func (m *Manager) run(z *zone) {
+	lastRefresh := time.Now()
+
+	for {
+		wait := initialRetry
+		current, err := db.GetZone(z.config.Zone, m.db)
+		if err != nil {
+			log.Printf("Failed to retrieve secondary zone '%s': %v", z.config.Zone, err)
+		}
+
+		if err := m.refresh(z, current); err != nil {
+			log.Printf("Failed to refresh secondary zone '%s' from %s: %v", z.config.Zone, z.config.Primary, err)
+
+			// Stop answering for the zone once the data is too old to trust
+			if current != nil {
+				wait = time.Duration(current.Retry) * time.Second
+				if time.Since(lastRefresh) > time.Duration(current.Expire)*time.Second {
+					m.setExpired(z, true)
+				}
+			} else {
+				m.setExpired(z, true)
+			}
+		} else {
+			lastRefresh = time.Now()
+			m.setExpired(z, false)
+			if current, err := db.GetZone(z.config.Zone, m.db); err == nil && current != nil {
+				wait = time.Duration(current.Refresh) * time.Second
+			}
+		}
+
+		select {
+		case <-z.notify:
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (m *Manager) setExpired(z *zone, expired bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	z.expired = expired
+}
+
+// Check the serial at the primary and transfer the zone if it changed
+func (m *Manager) refresh(z *zone, current *db.Zone) error {
+	name := dns.Fqdn(db.NormalizeZone(z.config.Zone))
+
+	query := new(dns.Msg)
+	query.SetQuestion(name, dns.TypeSOA)
+	m.sign(z, query)
+
+	c := &dns.Client{Net: "tcp", TsigSecret: m.tsigSecret(z)}
+	resp, _, err := c.Exchange(query, z.config.Primary)
+	if err != nil {
+		return err
+	} else if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) == 0 {
+		return fmt.Errorf("primary answered SOA query with %s", dns.RcodeToString[resp.Rcode])
+	}
+
+	soa, ok := resp.Answer[0].(*dns.SOA)
+	if !ok {
+		return fmt.Errorf("primary did not answer with an SOA")
+	} else if current != nil && current.Secondary() && !newer(soa.Serial, current.Serial) {
+		return nil
+	}
+
+	return m.transfer(z, soa)
+}
+
+// Replace the records of a zone with a full transfer from the primary
+func (m *Manager) transfer(z *zone, soa *dns.SOA) error {
+	query := new(dns.Msg)
+	query.SetAxfr(soa.Hdr.Name)
+	m.sign(z, query)
+
+	tr := &dns.Transfer{TsigSecret: m.tsigSecret(z)}
+	envelopes, err := tr.In(query, z.config.Primary)
+	if err != nil {
+		return err
+	}
+
+	var records []dns.RR
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			return envelope.Error
+		}
+		records = append(records, envelope.RR...)
+	}
+
+	// Only replace our records once the whole zone has arrived, and all at once
+	if err := m.db.Update(func(tx *bolt.Tx) error {
+		if err := db.DeleteZoneRecords(z.config.Zone, tx); err != nil {
+			return err
+		}
+		origin := dns.Fqdn(db.NormalizeZone(z.config.Zone))
+		for _, record := range records {
+			if record.Header().Rrtype == dns.TypeSOA {
+				continue
+			}
+
+			// Records are stored by name, so a primary must not be able to change names outside its zone
+			if !dns.IsSubDomain(origin, strings.ToLower(record.Header().Name)) {
+				log.Printf("Skipping record outside of secondary zone '%s': %s", z.config.Zone, record.String())
+				continue
+			}
+
+			if err := authority.Store(tx, record); err != nil {
+				log.Printf("Skipping record in secondary zone '%s': %v", z.config.Zone, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// The SOA of the primary is kept as the zone itself
+	saved := db.Zone{
+		Name:          db.NormalizeZone(z.config.Zone),
+		Nameserver:    soa.Ns,
+		Admin:         soa.Mbox,
+		Serial:        soa.Serial,
+		Refresh:       soa.Refresh,
+		Retry:         soa.Retry,
+		Expire:        soa.Expire,
+		TTL:           soa.Hdr.Ttl,
+		NegativeTTL:   soa.Minttl,
+		AllowTransfer: []string{},
+		TransferKeys:  []string{},
+		Primary:       z.config.Primary,
+	}
+	if current, err := db.GetZone(z.config.Zone, m.db); err == nil && current != nil {
+		saved.AllowTransfer = current.AllowTransfer
+		saved.TransferKeys = current.TransferKeys
+	}
+	if err := saved.Encode(m.db); err != nil {
+		return err
+	}
+
+	// Keep a journal so the zone can be passed on incrementally
+	if err := authority.Journal(saved.Name, m.db); err != nil {
+		log.Printf("Failed to journal secondary zone '%s': %v", saved.Name, err)
+	}
+
+	log.Printf("Transferred secondary zone '%s' at serial %d from %s", saved.Name, saved.Serial, z.config.Primary)
+	return nil
+}
+
+// Sign a query with the key of the zone, if it has one
+func (m *Manager) sign(z *zone, query *dns.Msg) {
+	if z.config.Key != "" {
+		query.SetTsig(dns.Fqdn(strings.ToLower(z.config.Key)), dns.HmacSHA256, 300, time.Now().Unix())
+	}
+}
+
+// Get the secrets to verify responses with, nil if the zone does not use TSIG
+func (m *Manager) tsigSecret(z *zone) map[string]string {
+	if z.config.Key == "" {
+		return nil
+	}
+	return m.secrets
+}
+
+// Compare serials using the serial number arithmetic of RFC 1982
+func newer(serial, current uint32) bool {
+	return serial != current && serial-current < 1<<31
+}
+
+// Check if an address belongs to the primary of a zone
+func fromPrimary(addr net.Addr, primary string) bool {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+
+	primaryHost, _, err := net.SplitHostPort(primary)
+	if err != nil {
+		primaryHost = primary
+	}
+
+	return net.ParseIP(host).Equal(net.ParseIP(primaryHost))
+}
@@ -0,0 +1,133 @@
+package upstream
+
+import (
+	"fmt"
+	"github.com/miekg/dns"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Anything that can answer queries for names outside our zones
+type Resolver interface {
+	Exchange(m *dns.Msg) (*dns.Msg, error)
+}
+
+// Upstream resolvers for names under a domain
+type Rule struct {
+	Domain     string   `mapstructure:"domain" json:"domain"`
+	Upstream   []string `mapstructure:"upstream" json:"upstream"`
+	Configured bool     `mapstructure:"-" json:"configured"`
+}
+
+// Chooses the upstream resolvers of a query by the longest domain suffix with a rule,
+// falling back to the general resolvers for everything else
+type Forwarders struct {
+	mu          sync.RWMutex
+	fallback    Resolver
+	rules       map[string]Rule
+	pools       map[string]*Pool
+	mode        string
+	timeout     time.Duration
+	maxFailures int
+	cooldown    time.Duration
+}
+
+// Create forwarders using the fallback for names without a rule. Pools of rules share the selection and health settings.
+func NewForwarders(fallback Resolver, mode string, timeout time.Duration, maxFailures int, cooldown time.Duration) *Forwarders {
+	return &Forwarders{
+		fallback:    fallback,
+		rules:       make(map[string]Rule),
+		pools:       make(map[string]*Pool),
+		mode:        mode,
+		timeout:     timeout,
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+	}
+}
+
+// Add or replace the rule for a domain
+func (f *Forwarders) Add(rule Rule) error {
+	if _, ok := dns.IsDomainName(rule.Domain); !ok || rule.Domain == "" {
+		return fmt.Errorf("invalid forwarding domain '%s'", rule.Domain)
+	}
+	for _, address := range rule.Upstream {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return fmt.Errorf("invalid upstream address '%s' for domain '%s': must be host:port", address, rule.Domain)
+		}
+	}
+
+	pool, err := New(rule.Upstream, f.mode, f.timeout, f.maxFailures, f.cooldown)
+	if err != nil {
+		return err
+	}
+
+	rule.Domain = canonical(rule.Domain)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rules[rule.Domain] = rule
+	f.pools[rule.Domain] = pool
+	return nil
+}
+
+// Remove the rule for a domain
+func (f *Forwarders) Remove(domain string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.rules, canonical(domain))
+	delete(f.pools, canonical(domain))
+}
+
+// Get the rule for exactly a domain
+func (f *Forwarders) Get(domain string) (Rule, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	rule, ok := f.rules[canonical(domain)]
+	return rule, ok
+}
+
+// Get every rule, ordered by domain
+func (f *Forwarders) Rules() []Rule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	rules := []Rule{}
+	for _, rule := range f.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Domain < rules[j].Domain })
+	return rules
+}
+
+// Get the resolvers to send a query for a name to
+func (f *Forwarders) For(name string) Resolver {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	// Walk up the name so the longest matching suffix wins
+	name = canonical(name)
+	for {
+		if pool, ok := f.pools[name]; ok {
+			return pool
+		} else if name == "." {
+			return f.fallback
+		}
+
+		if i := strings.Index(name, "."); i < len(name)-1 {
+			name = name[i+1:]
+		} else {
+			name = "."
+		}
+	}
+}
+
+// Convert a domain to how rules are keyed, lowercase and fully qualified
+func canonical(domain string) string {
+	return strings.ToLower(dns.Fqdn(domain))
+}
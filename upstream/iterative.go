@@ -0,0 +1,404 @@
+package upstream
+
+import (
+	"fmt"
+	"github.com/miekg/dns"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits protecting against referral loops and names that take too much work to resolve
+const (
+	maxQueries   = 100
+	maxDepth     = 8
+	maxCNAMEs    = 8
+	maxCacheSize = 10000
+)
+
+// The root servers from the IANA root hints
+var RootHints = []string{
+	"198.41.0.4", "199.9.14.201", "192.33.4.12", "199.7.91.13", "192.203.230.10", "192.5.5.241", "192.112.36.4",
+	"198.97.190.53", "192.36.148.17", "192.58.128.30", "193.0.14.129", "199.7.83.42", "202.12.27.33",
+}
+
+// Resolves names itself by following referrals down from the root servers instead of forwarding them
+type Iterative struct {
+	mu          sync.Mutex
+	roots       []string
+	port        string
+	timeout     time.Duration
+	minimise    bool
+	delegations map[string]cachedServers
+	addresses   map[string]cachedServers
+	rand        *rand.Rand
+}
+
+// Nameserver addresses along with when they must be looked up again
+type cachedServers struct {
+	servers []string
+	expires time.Time
+}
+
+// The work done for a single query, shared by the lookups of its nameservers
+type resolution struct {
+	queries int
+}
+
+// Create an iterative resolver starting from the root server addresses.
+// Every nameserver is contacted on the port, which is only changed to test against local servers.
+func NewIterative(roots []string, port int, timeout time.Duration, minimise bool) (*Iterative, error) {
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid nameserver port %d", port)
+	} else if len(roots) == 0 {
+		roots = RootHints
+	}
+
+	it := &Iterative{
+		port:        strconv.Itoa(port),
+		timeout:     timeout,
+		minimise:    minimise,
+		delegations: make(map[string]cachedServers),
+		addresses:   make(map[string]cachedServers),
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, root := range roots {
+		if net.ParseIP(root) == nil {
+			return nil, fmt.Errorf("invalid root hint '%s': must be an IP address", root)
+		}
+		it.roots = append(it.roots, net.JoinHostPort(root, it.port))
+	}
+
+	return it, nil
+}
+
+// Resolve the question of a query, following CNAMEs to the final answer
+func (it *Iterative) Exchange(m *dns.Msg) (*dns.Msg, error) {
+	if len(m.Question) == 0 {
+		return nil, fmt.Errorf("query has no question")
+	}
+
+	resp, err := it.resolve(m.Question[0].Name, m.Question[0].Qtype, 0, &resolution{})
+	if err != nil {
+		return nil, err
+	}
+
+	rcode := resp.Rcode
+	resp.SetReply(m)
+	resp.Rcode = rcode
+	resp.RecursionAvailable = true
+	return resp, nil
+}
+
+// Resolve a name, chasing CNAMEs across zones
+func (it *Iterative) resolve(name string, qtype uint16, depth int, state *resolution) (*dns.Msg, error) {
+	result := new(dns.Msg)
+	qname := dns.Fqdn(strings.ToLower(name))
+
+	for i := 0; i <= maxCNAMEs; i++ {
+		resp, err := it.lookup(qname, qtype, depth, state)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only keep records for the name asked about, anything else cannot be trusted
+		var target string
+		for _, rr := range resp.Answer {
+			if !strings.EqualFold(rr.Header().Name, qname) {
+				continue
+			}
+			result.Answer = append(result.Answer, rr)
+
+			if cname, ok := rr.(*dns.CNAME); ok && qtype != dns.TypeCNAME {
+				target = strings.ToLower(cname.Target)
+			}
+		}
+
+		// Answers with the requested type end the chain, even if a CNAME came along
+		if target == "" || hasType(result.Answer, target, qtype) {
+			result.Rcode = resp.Rcode
+			if len(result.Answer) == 0 || resp.Rcode != dns.RcodeSuccess {
+				result.Ns = soaOnly(resp.Ns)
+			}
+			return result, nil
+		}
+		qname = target
+	}
+
+	return nil, fmt.Errorf("too many CNAMEs resolving '%s'", name)
+}
+
+// Find the answer for a name by following referrals from the closest known delegation
+func (it *Iterative) lookup(qname string, qtype uint16, depth int, state *resolution) (*dns.Msg, error) {
+	zone, servers := it.closest(qname)
+	minimise := it.minimise
+	labels := dns.CountLabel(zone) + 1
+
+	for {
+		state.queries++
+		if state.queries > maxQueries {
+			return nil, fmt.Errorf("too many queries resolving '%s'", qname)
+		}
+
+		// Only reveal one more label than the servers being asked need to know
+		name, t := qname, qtype
+		if minimise && labels < dns.CountLabel(qname) {
+			name, t = lastLabels(qname, labels), dns.TypeA
+		}
+
+		resp, err := it.query(servers, name, t)
+		if err != nil {
+			return nil, err
+		}
+
+		// Follow referrals, which must move closer to the name so they cannot loop
+		if child, ns := referral(resp, zone, name); child != "" {
+			addresses, err := it.delegate(child, zone, ns, resp.Extra, depth, state)
+			if err != nil {
+				return nil, err
+			}
+			zone, servers = child, addresses
+			labels = dns.CountLabel(zone) + 1
+			continue
+		}
+
+		if name == qname {
+			return resp, nil
+		}
+
+		// Some servers wrongly deny names with children, so the full name is asked instead
+		if resp.Rcode == dns.RcodeNameError {
+			minimise = false
+			continue
+		}
+		labels++
+	}
+}
+
+// Get the closest cached delegation above a name, or the root servers
+func (it *Iterative) closest(qname string) (string, []string) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	now := time.Now()
+	for _, i := range append(dns.Split(qname), len(qname)-1) {
+		zone := qname[i:]
+		if zone == "" {
+			zone = "."
+		}
+		if cached, ok := it.delegations[zone]; ok && now.Before(cached.expires) {
+			return zone, cached.servers
+		}
+	}
+	return ".", it.roots
+}
+
+// Get the addresses of the nameservers of a delegation, using glue the parent is authoritative for
+// and looking up the rest, then cache them
+func (it *Iterative) delegate(child, parent string, ns []*dns.NS, extra []dns.RR, depth int, state *resolution) ([]string, error) {
+	ttl := ns[0].Hdr.Ttl
+	var addresses []string
+	var missing []string
+	for _, record := range ns {
+		if record.Hdr.Ttl < ttl {
+			ttl = record.Hdr.Ttl
+		}
+
+		host := strings.ToLower(record.Ns)
+		glue := false
+		if dns.IsSubDomain(parent, host) {
+			for _, rr := range extra {
+				if !strings.EqualFold(rr.Header().Name, host) {
+					continue
+				}
+				switch address := rr.(type) {
+				case *dns.A:
+					addresses = append(addresses, net.JoinHostPort(address.A.String(), it.port))
+					glue = true
+				case *dns.AAAA:
+					addresses = append(addresses, net.JoinHostPort(address.AAAA.String(), it.port))
+					glue = true
+				}
+			}
+		}
+		if !glue {
+			missing = append(missing, host)
+		}
+	}
+
+	// Nameservers without glue are only looked up when needed, since one working server is enough
+	if len(addresses) == 0 {
+		if depth >= maxDepth {
+			return nil, fmt.Errorf("nameserver lookups for '%s' nested too deeply", child)
+		}
+
+		var lastErr error
+		for _, host := range missing {
+			found, err := it.addressesOf(host, depth+1, state)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			addresses = append(addresses, found...)
+			if len(addresses) != 0 {
+				break
+			}
+		}
+		if len(addresses) == 0 {
+			return nil, fmt.Errorf("no addresses for nameservers of '%s': %v", child, lastErr)
+		}
+	}
+
+	it.store(it.delegations, child, addresses, ttl)
+	return addresses, nil
+}
+
+// Look up the addresses of a nameserver, using cached ones if they have not expired
+func (it *Iterative) addressesOf(host string, depth int, state *resolution) ([]string, error) {
+	it.mu.Lock()
+	cached, ok := it.addresses[host]
+	it.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.servers, nil
+	}
+
+	var addresses []string
+	ttl := uint32(0)
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		resp, err := it.resolve(host, qtype, depth, state)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rr := range resp.Answer {
+			switch address := rr.(type) {
+			case *dns.A:
+				addresses = append(addresses, net.JoinHostPort(address.A.String(), it.port))
+			case *dns.AAAA:
+				addresses = append(addresses, net.JoinHostPort(address.AAAA.String(), it.port))
+			default:
+				continue
+			}
+			if ttl == 0 || rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+			}
+		}
+
+		// IPv6 addresses are only needed for nameservers without IPv4 ones
+		if len(addresses) != 0 {
+			break
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("nameserver '%s' has no addresses", host)
+	}
+	it.store(it.addresses, host, addresses, ttl)
+	return addresses, nil
+}
+
+// Cache servers for their TTL, clearing the cache once it is full
+func (it *Iterative) store(cache map[string]cachedServers, key string, servers []string, ttl uint32) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if len(cache) >= maxCacheSize {
+		for k := range cache {
+			delete(cache, k)
+		}
+	}
+	cache[key] = cachedServers{servers: servers, expires: time.Now().Add(time.Duration(ttl) * time.Second)}
+}
+
+// Ask the servers of a zone a question without recursion, moving on to the next server whenever one fails
+func (it *Iterative) query(servers []string, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.RecursionDesired = false
+	m.SetEdns0(4096, false)
+
+	it.mu.Lock()
+	order := it.rand.Perm(len(servers))
+	it.mu.Unlock()
+
+	var lastErr error
+	for _, i := range order {
+		c := &dns.Client{Net: "udp", Timeout: it.timeout}
+		resp, _, err := c.Exchange(m, servers[i])
+		if err == nil && resp.Truncated {
+			c.Net = "tcp"
+			resp, _, err = c.Exchange(m, servers[i])
+		}
+
+		if err == nil && resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+			err = fmt.Errorf("nameserver '%s' answered with %s", servers[i], dns.RcodeToString[resp.Rcode])
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return resp, nil
+	}
+
+	return nil, fmt.Errorf("all nameservers failed for '%s': %v", name, lastErr)
+}
+
+// Get the zone and nameservers a response delegates to, if it is a referral below the current zone towards the name
+func referral(resp *dns.Msg, zone, name string) (string, []*dns.NS) {
+	if len(resp.Answer) != 0 || resp.Rcode != dns.RcodeSuccess {
+		return "", nil
+	}
+
+	var child string
+	var ns []*dns.NS
+	for _, rr := range resp.Ns {
+		record, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+
+		owner := strings.ToLower(record.Hdr.Name)
+		if child == "" {
+			child = owner
+		}
+		if owner == child {
+			ns = append(ns, record)
+		}
+	}
+
+	// Referrals sideways or upwards would lead in circles
+	if child == "" || dns.CountLabel(child) <= dns.CountLabel(zone) || !dns.IsSubDomain(zone, child) || !dns.IsSubDomain(child, name) {
+		return "", nil
+	}
+	return child, ns
+}
+
+// Check if an answer has records of a type for a name
+func hasType(answer []dns.RR, name string, qtype uint16) bool {
+	for _, rr := range answer {
+		if rr.Header().Rrtype == qtype && strings.EqualFold(rr.Header().Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Keep only the SOA records of an authority section, which negative answers are cached by
+func soaOnly(ns []dns.RR) []dns.RR {
+	var records []dns.RR
+	for _, rr := range ns {
+		if rr.Header().Rrtype == dns.TypeSOA {
+			records = append(records, rr)
+		}
+	}
+	return records
+}
+
+// Get the last labels of a name
+func lastLabels(name string, labels int) string {
+	indexes := dns.Split(name)
+	return name[indexes[len(indexes)-labels]:]
+}
@@ -0,0 +1,266 @@
+package upstream
+
+import (
+	"github.com/miekg/dns"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// An authoritative server for a single zone, where NS records below the origin are delegations
+type fakeZone struct {
+	origin  string
+	records []dns.RR
+
+	mu      sync.Mutex
+	queries int
+}
+
+func (z *fakeZone) ServeDNS(w dns.ResponseWriter, m *dns.Msg) {
+	z.mu.Lock()
+	z.queries++
+	z.mu.Unlock()
+
+	r := new(dns.Msg)
+	r.SetReply(m)
+	name := strings.ToLower(m.Question[0].Name)
+
+	// Refer names below a delegation to its nameservers, with any addresses we have for them as glue
+	for _, rr := range z.records {
+		if ns, ok := rr.(*dns.NS); ok && ns.Hdr.Name != z.origin && dns.IsSubDomain(ns.Hdr.Name, name) {
+			r.Ns = append(r.Ns, z.find(ns.Hdr.Name, dns.TypeNS)...)
+			for _, delegation := range r.Ns {
+				r.Extra = append(r.Extra, z.find(delegation.(*dns.NS).Ns, dns.TypeA)...)
+			}
+			w.WriteMsg(r)
+			return
+		}
+	}
+
+	r.Authoritative = true
+	r.Answer = append(z.find(name, m.Question[0].Qtype), z.find(name, dns.TypeCNAME)...)
+	if len(r.Answer) == 0 {
+		r.Rcode = dns.RcodeNameError
+		for _, rr := range z.records {
+			if dns.IsSubDomain(name, rr.Header().Name) {
+				r.Rcode = dns.RcodeSuccess
+			}
+		}
+		r.Ns = []dns.RR{newRR(z.origin + " 60 IN SOA ns. hostmaster. 1 3600 600 86400 60")}
+	}
+	w.WriteMsg(r)
+}
+
+// Get the records of a type for a name
+func (z *fakeZone) find(name string, qtype uint16) []dns.RR {
+	var found []dns.RR
+	for _, rr := range z.records {
+		if rr.Header().Name == name && rr.Header().Rrtype == qtype {
+			found = append(found, rr)
+		}
+	}
+	return found
+}
+
+func (z *fakeZone) count() int {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return z.queries
+}
+
+func newRR(s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		panic(err)
+	}
+	return rr
+}
+
+// Start a server for each address on the same port, returning the port and a function stopping them
+func startServers(t *testing.T, handlers map[string]dns.Handler) (int, func()) {
+	var servers []*dns.Server
+	stop := func() {
+		for _, server := range servers {
+			server.Shutdown()
+		}
+	}
+
+	port := 0
+	for _, ip := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.3", "127.0.0.4", "127.0.0.5", "127.0.0.9"} {
+		handler, ok := handlers[ip]
+		if !ok {
+			continue
+		}
+
+		pc, err := net.ListenPacket("udp", net.JoinHostPort(ip, strconv.Itoa(port)))
+		if err != nil {
+			stop()
+			t.Fatalf("failed to listen on %s: %v", ip, err)
+		}
+		port = pc.LocalAddr().(*net.UDPAddr).Port
+
+		server := &dns.Server{PacketConn: pc, Handler: handler}
+		servers = append(servers, server)
+		go server.ActivateAndServe()
+	}
+
+	return port, stop
+}
+
+// The zones the tests resolve through:
+//   - the root delegates test. and net. with in-bailiwick glue
+//   - test. delegates example.test. with glue, and other.test. with out-of-bailiwick glue pointing at a poisoned server
+//   - other.test. is really served by ns.example.net., whose address comes from net.
+type hierarchy struct {
+	root, tld, example, other, net, poisoned *fakeZone
+}
+
+func newHierarchy() *hierarchy {
+	return &hierarchy{
+		root: &fakeZone{origin: ".", records: []dns.RR{
+			newRR("test. 3600 IN NS ns.test."),
+			newRR("ns.test. 3600 IN A 127.0.0.2"),
+			newRR("net. 3600 IN NS ns.net."),
+			newRR("ns.net. 3600 IN A 127.0.0.5"),
+		}},
+		tld: &fakeZone{origin: "test.", records: []dns.RR{
+			newRR("example.test. 3600 IN NS ns1.example.test."),
+			newRR("ns1.example.test. 3600 IN A 127.0.0.3"),
+			newRR("other.test. 3600 IN NS ns.example.net."),
+			newRR("ns.example.net. 3600 IN A 127.0.0.9"),
+		}},
+		example: &fakeZone{origin: "example.test.", records: []dns.RR{
+			newRR("www.example.test. 300 IN A 192.0.2.1"),
+			newRR("alias.example.test. 300 IN CNAME host.other.test."),
+		}},
+		other: &fakeZone{origin: "other.test.", records: []dns.RR{
+			newRR("host.other.test. 300 IN A 192.0.2.2"),
+		}},
+		net: &fakeZone{origin: "net.", records: []dns.RR{
+			newRR("ns.example.net. 3600 IN A 127.0.0.4"),
+		}},
+		poisoned: &fakeZone{origin: "other.test.", records: []dns.RR{
+			newRR("host.other.test. 300 IN A 203.0.113.66"),
+		}},
+	}
+}
+
+func (h *hierarchy) start(t *testing.T) (*Iterative, func()) {
+	port, stop := startServers(t, map[string]dns.Handler{
+		"127.0.0.1": h.root,
+		"127.0.0.2": h.tld,
+		"127.0.0.3": h.example,
+		"127.0.0.4": h.other,
+		"127.0.0.5": h.net,
+		"127.0.0.9": h.poisoned,
+	})
+
+	it, err := NewIterative([]string{"127.0.0.1"}, port, time.Second, true)
+	if err != nil {
+		stop()
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+	return it, stop
+}
+
+func exchange(t *testing.T, it *Iterative, name string, qtype uint16) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+
+	resp, err := it.Exchange(m)
+	if err != nil {
+		t.Fatalf("failed to resolve '%s': %v", name, err)
+	}
+	return resp
+}
+
+func addresses(answer []dns.RR) []string {
+	var found []string
+	for _, rr := range answer {
+		if a, ok := rr.(*dns.A); ok {
+			found = append(found, a.A.String())
+		}
+	}
+	return found
+}
+
+func TestIterativeFollowsReferralWithGlue(t *testing.T) {
+	h := newHierarchy()
+	it, stop := h.start(t)
+	defer stop()
+
+	resp := exchange(t, it, "www.example.test.", dns.TypeA)
+	if resp.Rcode != dns.RcodeSuccess {
+		t.Fatalf("expected NOERROR, got %s", dns.RcodeToString[resp.Rcode])
+	} else if got := addresses(resp.Answer); len(got) != 1 || got[0] != "192.0.2.1" {
+		t.Fatalf("expected 192.0.2.1, got %v", got)
+	}
+
+	// The glue for ns1.example.test. is used instead of looking it up
+	if h.example.count() == 0 {
+		t.Fatal("expected the example.test. server to be queried")
+	} else if h.net.count() != 0 {
+		t.Fatal("expected no queries outside the delegation path")
+	}
+}
+
+func TestIterativeIgnoresOutOfBailiwickGlue(t *testing.T) {
+	h := newHierarchy()
+	it, stop := h.start(t)
+	defer stop()
+
+	resp := exchange(t, it, "host.other.test.", dns.TypeA)
+	if got := addresses(resp.Answer); len(got) != 1 || got[0] != "192.0.2.2" {
+		t.Fatalf("expected 192.0.2.2, got %v", got)
+	}
+
+	// test. is not authoritative for ns.example.net., so its address must come from net.
+	if h.poisoned.count() != 0 {
+		t.Fatal("expected the out-of-bailiwick glue to be ignored")
+	} else if h.net.count() == 0 {
+		t.Fatal("expected the nameserver address to be looked up")
+	}
+}
+
+func TestIterativeFollowsCNAMEToAnotherZone(t *testing.T) {
+	h := newHierarchy()
+	it, stop := h.start(t)
+	defer stop()
+
+	resp := exchange(t, it, "alias.example.test.", dns.TypeA)
+	if len(resp.Answer) != 2 {
+		t.Fatalf("expected a CNAME and an address, got %v", resp.Answer)
+	} else if cname, ok := resp.Answer[0].(*dns.CNAME); !ok || cname.Target != "host.other.test." {
+		t.Fatalf("expected a CNAME to host.other.test., got %v", resp.Answer[0])
+	} else if got := addresses(resp.Answer); len(got) != 1 || got[0] != "192.0.2.2" {
+		t.Fatalf("expected 192.0.2.2, got %v", got)
+	}
+}
+
+func TestIterativeQueryLimit(t *testing.T) {
+	// Every name is delegated one label further down to the same server, so resolution never ends
+	endless := dns.HandlerFunc(func(w dns.ResponseWriter, m *dns.Msg) {
+		r := new(dns.Msg)
+		r.SetReply(m)
+		name := strings.ToLower(m.Question[0].Name)
+		r.Ns = []dns.RR{newRR(name + " 3600 IN NS ns." + name)}
+		r.Extra = []dns.RR{newRR("ns." + name + " 3600 IN A 127.0.0.1")}
+		w.WriteMsg(r)
+	})
+	port, stop := startServers(t, map[string]dns.Handler{"127.0.0.1": endless})
+	defer stop()
+
+	it, err := NewIterative([]string{"127.0.0.1"}, port, time.Second, true)
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(strings.Repeat("a.", maxQueries+10)+"test.", dns.TypeA)
+	if _, err := it.Exchange(m); err == nil || !strings.Contains(err.Error(), "too many queries") {
+		t.Fatalf("expected the query limit to be reached, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package upstream
+
+import (
+	"fmt"
+	"github.com/miekg/dns"
+	"net"
+)
+
+// Address families used in client subnet options
+const (
+	familyIPv4 = 1
+	familyIPv6 = 2
+)
+
+// Decides which client subnet, if any, is sent upstream as described in RFC 7871
+type Subnets struct {
+	enabled  bool
+	ipv4     uint8
+	ipv6     uint8
+	override *dns.EDNS0_SUBNET
+}
+
+// Create the client subnet settings. Client addresses are truncated to the prefix
+// lengths for their family, unless an override subnet is sent for every client.
+func NewSubnets(enabled bool, ipv4Prefix, ipv6Prefix int, override string) (*Subnets, error) {
+	if ipv4Prefix < 0 || ipv4Prefix > 32 {
+		return nil, fmt.Errorf("IPv4 client subnet prefix must be between 0 and 32")
+	} else if ipv6Prefix < 0 || ipv6Prefix > 128 {
+		return nil, fmt.Errorf("IPv6 client subnet prefix must be between 0 and 128")
+	}
+
+	s := &Subnets{enabled: enabled, ipv4: uint8(ipv4Prefix), ipv6: uint8(ipv6Prefix)}
+	if override != "" {
+		ip, prefix, err := net.ParseCIDR(override)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client subnet override '%s': %v", override, err)
+		}
+		length, _ := prefix.Mask.Size()
+		s.override = subnet(ip, uint8(length))
+	}
+
+	return s, nil
+}
+
+// Get the option to send upstream for a query, nil if no subnet should be sent.
+// Subnets given by the client are used in place of its address, but are still truncated.
+func (s *Subnets) Option(client net.IP, query *dns.Msg) *dns.EDNS0_SUBNET {
+	if !s.enabled {
+		return nil
+	}
+
+	if given := SubnetOption(query); given != nil {
+		// A source prefix of 0 asks for the client's address not to be sent
+		if given.SourceNetmask == 0 {
+			return nil
+		}
+
+		limit := s.ipv4
+		if given.Family == familyIPv6 {
+			limit = s.ipv6
+		}
+		if given.SourceNetmask < limit {
+			limit = given.SourceNetmask
+		}
+		return subnet(given.Address, limit)
+	} else if s.override != nil {
+		return subnet(s.override.Address, s.override.SourceNetmask)
+	}
+
+	// Private and local addresses mean nothing to upstream servers
+	if client == nil || !client.IsGlobalUnicast() || private(client) {
+		return nil
+	} else if client.To4() != nil {
+		return subnet(client, s.ipv4)
+	}
+	return subnet(client, s.ipv6)
+}
+
+// Get the client subnet option of a message, if it has one
+func SubnetOption(m *dns.Msg) *dns.EDNS0_SUBNET {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+
+	for _, option := range opt.Option {
+		if ecs, ok := option.(*dns.EDNS0_SUBNET); ok {
+			return ecs
+		}
+	}
+	return nil
+}
+
+// Build a client subnet option with the address truncated to the prefix length
+func subnet(ip net.IP, prefix uint8) *dns.EDNS0_SUBNET {
+	option := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, SourceNetmask: prefix}
+	if ip4 := ip.To4(); ip4 != nil {
+		if prefix > 32 {
+			option.SourceNetmask = 32
+		}
+		option.Family = familyIPv4
+		option.Address = ip4.Mask(net.CIDRMask(int(option.SourceNetmask), 32))
+	} else {
+		if prefix > 128 {
+			option.SourceNetmask = 128
+		}
+		option.Family = familyIPv6
+		option.Address = ip.Mask(net.CIDRMask(int(option.SourceNetmask), 128))
+	}
+	return option
+}
+
+// Check if an address is in a private range
+func private(ip net.IP) bool {
+	for _, network := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		if _, prefix, _ := net.ParseCIDR(network); prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package upstream
+
+import (
+	"fmt"
+	"github.com/miekg/dns"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Ways of choosing which upstream server to try first
+const (
+	Random        = "random"
+	RoundRobin    = "round-robin"
+	LowestLatency = "lowest-latency"
+)
+
+// A set of upstream resolvers with passive health tracking
+type Pool struct {
+	mu          sync.Mutex
+	servers     []*server
+	mode        string
+	next        int
+	rand        *rand.Rand
+	timeout     time.Duration
+	maxFailures int
+	cooldown    time.Duration
+}
+
+// The health of a single upstream resolver
+type server struct {
+	address   string
+	failures  int
+	downUntil time.Time
+	latency   time.Duration
+}
+
+// Create a pool of upstream resolvers. Servers are taken out of rotation for the
+// cooldown after failing maxFailures times in a row.
+func New(addresses []string, mode string, timeout time.Duration, maxFailures int, cooldown time.Duration) (*Pool, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("at least 1 upstream resolver is required")
+	} else if mode != Random && mode != RoundRobin && mode != LowestLatency {
+		return nil, fmt.Errorf("unknown upstream selection mode '%s'", mode)
+	}
+
+	p := &Pool{
+		mode:        mode,
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		timeout:     timeout,
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+	}
+	for _, address := range addresses {
+		p.servers = append(p.servers, &server{address: address})
+	}
+
+	return p, nil
+}
+
+// Send a query upstream, moving on to the next server whenever one fails or cannot answer
+func (p *Pool) Exchange(m *dns.Msg) (*dns.Msg, error) {
+	var lastErr error
+	var lastResp *dns.Msg
+
+	for _, s := range p.order() {
+		start := time.Now()
+		resp, err := p.exchange(m, s.address)
+		if err != nil {
+			p.failed(s)
+			lastErr = err
+			continue
+		}
+		p.succeeded(s, time.Since(start))
+
+		// Servers answering SERVFAIL or REFUSED are up, but another one may still be able to answer
+		if resp.Rcode == dns.RcodeServerFailure || resp.Rcode == dns.RcodeRefused {
+			lastResp = resp
+			continue
+		}
+		return resp, nil
+	}
+
+	if lastResp != nil {
+		return lastResp, nil
+	}
+	return nil, fmt.Errorf("all upstream resolvers failed: %v", lastErr)
+}
+
+// Query a single server, retrying over TCP if the UDP answer was truncated
+func (p *Pool) exchange(m *dns.Msg, address string) (*dns.Msg, error) {
+	c := &dns.Client{Net: "udp", Timeout: p.timeout}
+	resp, _, err := c.Exchange(m, address)
+	if err != nil || !resp.Truncated {
+		return resp, err
+	}
+
+	c.Net = "tcp"
+	resp, _, err = c.Exchange(m, address)
+	return resp, err
+}
+
+// Get the order to try servers in, with healthy servers first
+func (p *Pool) order() []*server {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	servers := make([]*server, len(p.servers))
+	switch p.mode {
+	case Random:
+		for i, j := range p.rand.Perm(len(p.servers)) {
+			servers[i] = p.servers[j]
+		}
+	case RoundRobin:
+		for i := range p.servers {
+			servers[i] = p.servers[(p.next+i)%len(p.servers)]
+		}
+		p.next = (p.next + 1) % len(p.servers)
+	case LowestLatency:
+		copy(servers, p.servers)
+		sort.SliceStable(servers, func(i, j int) bool { return servers[i].latency < servers[j].latency })
+	}
+
+	// Servers still cooling down are only tried once every healthy server has failed
+	now := time.Now()
+	sort.SliceStable(servers, func(i, j int) bool {
+		return !now.Before(servers[i].downUntil) && now.Before(servers[j].downUntil)
+	})
+
+	return servers
+}
+
+// Record a failed query, taking the server out of rotation if it keeps failing
+func (p *Pool) failed(s *server) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s.failures++
+	if s.failures == p.maxFailures {
+		log.Printf("Upstream '%s' failed %d times in a row, removing from rotation for %s", s.address, s.failures, p.cooldown)
+	}
+	if s.failures >= p.maxFailures {
+		s.downUntil = time.Now().Add(p.cooldown)
+	}
+}
+
+// Record a successful query along with how long it took
+func (p *Pool) succeeded(s *server, rtt time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s.failures = 0
+	s.downUntil = time.Time{}
+
+	// Smooth the latency so a single slow query does not reorder servers
+	if s.latency == 0 {
+		s.latency = rtt
+	} else {
+		s.latency = (s.latency*7 + rtt) / 8
+	}
+}
+
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"github.com/miekg/dns"
+	"net"
+	"strings"
+)
+
+// A list of client networks and TSIG keys allowed to do something
+type ACL struct {
+	networks []*net.IPNet
+	keys     map[string]bool
+}
+
+// Parse the networks and key names of an ACL. Networks can be prefixes or single addresses.
+func NewACL(networks, keys []string) (*ACL, error) {
+	acl := &ACL{keys: make(map[string]bool)}
+
+	for _, network := range networks {
+		// Single addresses are treated as a prefix of their full length
+		if !strings.Contains(network, "/") {
+			if ip := net.ParseIP(network); ip != nil && ip.To4() != nil {
+				network += "/32"
+			} else {
+				network += "/128"
+			}
+		}
+
+		_, prefix, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network '%s': %v", network, err)
+		}
+		acl.networks = append(acl.networks, prefix)
+	}
+	for _, key := range keys {
+		acl.keys[dns.Fqdn(strings.ToLower(key))] = true
+	}
+
+	return acl, nil
+}
+
+// Check if an ACL has no entries
+func (a *ACL) Empty() bool {
+	return len(a.networks) == 0 && len(a.keys) == 0
+}
+
+// Check if a client is in the ACL by its address or the TSIG key its query was verified with
+func (a *ACL) Allowed(ip net.IP, key string) bool {
+	if key != "" && a.keys[dns.Fqdn(strings.ToLower(key))] {
+		return true
+	}
+
+	for _, network := range a.networks {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// Loads a certificate and key pair from disk, reloading them whenever either file changes
+type CertificateLoader struct {
+	mu          sync.Mutex
+	certFile    string
+	keyFile     string
+	certificate *tls.Certificate
+	modified    time.Time
+}
+
+// Create a certificate loader, failing if the initial certificate cannot be loaded
+func NewCertificateLoader(certFile, keyFile string) (*CertificateLoader, error) {
+	l := &CertificateLoader{certFile: certFile, keyFile: keyFile}
+	if _, err := l.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Get the current certificate, for use as tls.Config.GetCertificate
+func (l *CertificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Use the newest modification time of the pair to detect changes
+	modified, err := latestModification(l.certFile, l.keyFile)
+	if err != nil {
+		if l.certificate != nil {
+			return l.certificate, nil
+		}
+		return nil, err
+	} else if l.certificate != nil && !modified.After(l.modified) {
+		return l.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		// Keep serving the previous certificate while files are being replaced
+		if l.certificate != nil {
+			return l.certificate, nil
+		}
+		return nil, err
+	}
+
+	l.certificate = &certificate
+	l.modified = modified
+	return l.certificate, nil
+}
+
+// Get the most recent modification time of a set of files
+func latestModification(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
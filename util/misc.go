@@ -2,7 +2,9 @@ package util
 
 import (
 	"fmt"
-	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/miekg/dns"
+	"reflect"
+	"strings"
 )
 
 // Check if a value exists within a map
@@ -13,7 +15,7 @@ func Exists(m map[string]interface{}, key string) bool {
 
 // Convert []interface to []string)
 func ConvertArrayToString(iarr []interface{}) ([]string, error) {
-	strings := make([]string, len(iarr))
+	strings := make([]string, 0, len(iarr))
 
 	for _, v := range iarr {
 		if s, ok := v.(string); !ok {
@@ -42,10 +44,10 @@ func RemoveDuplicates(arr []string) []string {
 	return result
 }
 
-// Check if a record does not exist
-func RecordDoesNotExist(r db.Record) bool {
-	// This is a REALLY big hack, but its the best way I could think of
-	return fmt.Sprintf("%v", r) == "<nil>"
+// Check if a record set does not exist
+func RecordDoesNotExist(set interface{}) bool {
+	// Every getter returns a slice of its record type
+	return reflect.ValueOf(set).Len() == 0
 }
 
 // Check if value in array
@@ -57,3 +59,14 @@ func StringInArray(val string, list []string) bool {
 	}
 	return false
 }
+
+// Check if a record name is a valid domain, allowing a wildcard as the leftmost label
+func ValidRecordName(name string) bool {
+	name = strings.TrimPrefix(strings.TrimSuffix(name, "."), "*.")
+	if _, ok := dns.IsDomainName(name); !ok || name == "" {
+		return false
+	}
+
+	// An asterisk anywhere else would be ambiguous with the storage key separator
+	return !strings.Contains(name, "*")
+}
@@ -85,7 +85,7 @@ func ValidateBody(body map[string]interface{}, keys []string, options map[string
 		case "stringarray":
 			if !Types.StringArray(body[key]) {
 				return "field '" + key + "' must be an array of strings", valid
-			} else if text, _ := ConvertArrayToString(body["text"].([]interface{})); len(text) < 1 {
+			} else if text, _ := ConvertArrayToString(body[key].([]interface{})); len(text) < 1 && options[key]["allowEmpty"] != "true" {
 				return "field '" + key + "' must be of at least length 1", valid
 			}
 		}
@@ -0,0 +1,79 @@
+package views
+
+import (
+	"fmt"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"net"
+	"strings"
+)
+
+// A view with its own records, selected by the client's network or TSIG key
+type Config struct {
+	Name     string   `mapstructure:"name"`
+	Networks []string `mapstructure:"networks"`
+	Keys     []string `mapstructure:"tsig-keys"`
+}
+
+// A configured view with its clients parsed
+type view struct {
+	name    string
+	clients *util.ACL
+}
+
+// Selects the view to answer a client from, in the order configured
+type Views struct {
+	views []view
+}
+
+// Parse the configured views and set up storage for their records
+func New(configs []Config, secrets map[string]string, database *bolt.DB) (*Views, error) {
+	v := &Views{}
+
+	for _, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("views require a name")
+		} else if len(config.Networks) == 0 && len(config.Keys) == 0 {
+			return nil, fmt.Errorf("view '%s' must have networks or TSIG keys to select it", config.Name)
+		}
+
+		for _, key := range config.Keys {
+			if _, ok := secrets[dns.Fqdn(strings.ToLower(key))]; !ok {
+				return nil, fmt.Errorf("unknown TSIG key '%s' for view '%s'", key, config.Name)
+			}
+		}
+		clients, err := util.NewACL(config.Networks, config.Keys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid view '%s': %v", config.Name, err)
+		}
+
+		if err := db.SetupView(config.Name, database); err != nil {
+			return nil, fmt.Errorf("failed to set up view '%s': %v", config.Name, err)
+		}
+		v.views = append(v.views, view{name: config.Name, clients: clients})
+	}
+
+	return v, nil
+}
+
+// Get the view for a client, falling back to the default view if none match.
+// The key is the name of the TSIG key the query was verified with, if any.
+func (v *Views) Select(ip net.IP, key string) string {
+	for _, view := range v.views {
+		if view.clients.Allowed(ip, key) {
+			return view.name
+		}
+	}
+	return ""
+}
+
+// Get the names of all configured views
+func (v *Views) Names() []string {
+	names := []string{}
+	for _, view := range v.views {
+		names = append(names, view.name)
+	}
+	return names
+}
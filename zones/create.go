@@ -0,0 +1,121 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/authority"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net/http"
+)
+
+// Default SOA timers, following RIPE-203
+const (
+	defaultRefresh     = 86400
+	defaultRetry       = 7200
+	defaultExpire      = 3600000
+	defaultNegativeTTL = 300
+)
+
+// Handle the creation of zones
+func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, valid := util.ValidateBody(body, []string{"name", "nameserver", "admin", "refresh", "retry", "expire", "ttl", "negative-ttl", "allow-transfer", "transfer-keys"}, map[string]map[string]string{
+		"name": {"type": "string", "required": "true"},
+		"nameserver": {"type": "string", "required": "true"},
+		"admin": {"type": "string", "required": "true"},
+		"refresh": {"type": "uint32", "required": "false"},
+		"retry": {"type": "uint32", "required": "false"},
+		"expire": {"type": "uint32", "required": "false"},
+		"ttl": {"type": "uint32", "required": "false"},
+		"negative-ttl": {"type": "uint32", "required": "false"},
+		"allow-transfer": {"type": "stringarray", "required": "false", "allowEmpty": "true"},
+		"transfer-keys": {"type": "stringarray", "required": "false", "allowEmpty": "true"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	} else if _, ok := dns.IsDomainName(body["name"].(string)); !ok || db.NormalizeZone(body["name"].(string)) == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "invalid zone name")
+		return
+	}
+
+	// Check if already exists
+	if zone, err := db.GetZone(body["name"].(string), database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve existing zones: "+err.Error())
+		return
+	} else if zone != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "zone already exists")
+		return
+	}
+
+	// Use defaults for missing timers, a TTL of 0 uses the server default
+	timers := map[string]uint32{"refresh": defaultRefresh, "retry": defaultRetry, "expire": defaultExpire, "ttl": 0, "negative-ttl": defaultNegativeTTL}
+	for field := range timers {
+		if valid[field] {
+			timers[field] = uint32(body[field].(float64))
+		}
+	}
+
+	// Write zone to database
+	z := db.NewZone(body["name"].(string), body["nameserver"].(string), body["admin"].(string), timers["refresh"], timers["retry"], timers["expire"], timers["ttl"], timers["negative-ttl"])
+	if valid["allow-transfer"] {
+		z.AllowTransfer, _ = util.ConvertArrayToString(body["allow-transfer"].([]interface{}))
+	}
+	if valid["transfer-keys"] {
+		z.TransferKeys, _ = util.ConvertArrayToString(body["transfer-keys"].([]interface{}))
+	}
+	if err := z.Encode(database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write zone to database: "+err.Error())
+		return
+	}
+
+	// Start the journal used for incremental transfers
+	if err := authority.Journal(z.Name, database); err != nil {
+		log.Printf("Failed to journal zone '%s': %v", z.Name, err)
+	}
+
+	util.Responses.Success(w)
+}
@@ -0,0 +1,44 @@
+package zones
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+// Handle requests regarding zones
+func AllZonesHandler(db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			list(w, r, db)
+			return
+		case "POST":
+			create(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests for methods regarding singular zones
+func SingleZoneHandler(path string, db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			read(w, r, path, db)
+			return
+		case "PUT":
+			update(w, r, path, db)
+			return
+		case "DELETE":
+			deleteZone(w, r, path, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package zones
+
+import (
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+func read(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB) {
+	// Validate initial request with type and header
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "zone must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	if _, err := db.TokenFromString(r.Header.Get("Authorization"), database); err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get from database
+	zone, err := db.GetZone(r.URL.Path[len(path):], database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve zone: "+err.Error())
+		return
+	} else if zone == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "specified zone does not exist")
+		return
+	}
+
+	util.Responses.SuccessWithData(w, zone)
+}
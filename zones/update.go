@@ -0,0 +1,132 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/akrantz01/krantz.dev/dns/authority"
+	"github.com/akrantz01/krantz.dev/dns/db"
+	"github.com/akrantz01/krantz.dev/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net/http"
+)
+
+func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB) {
+	// Validate initial request with type, body exists, and headers
+	if r.Method != "PUT" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "zone must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check user role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, valid := util.ValidateBody(body, []string{"nameserver", "admin", "refresh", "retry", "expire", "ttl", "negative-ttl", "allow-transfer", "transfer-keys"}, map[string]map[string]string{
+		"nameserver": {"type": "string", "required": "false"},
+		"admin": {"type": "string", "required": "false"},
+		"refresh": {"type": "uint32", "required": "false"},
+		"retry": {"type": "uint32", "required": "false"},
+		"expire": {"type": "uint32", "required": "false"},
+		"ttl": {"type": "uint32", "required": "false"},
+		"negative-ttl": {"type": "uint32", "required": "false"},
+		"allow-transfer": {"type": "stringarray", "required": "false", "allowEmpty": "true"},
+		"transfer-keys": {"type": "stringarray", "required": "false", "allowEmpty": "true"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	// Get zone from database
+	zone, err := db.GetZone(r.URL.Path[len(path):], database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve zone: "+err.Error())
+		return
+	} else if zone == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "specified zone does not exist")
+		return
+	} else if zone.Secondary() {
+		util.Responses.Error(w, http.StatusForbidden, "secondary zones are managed by their primary")
+		return
+	}
+
+	// Update values if they exist in the body
+	if valid["nameserver"] {
+		zone.Nameserver = body["nameserver"].(string)
+	}
+	if valid["admin"] {
+		zone.Admin = body["admin"].(string)
+	}
+	if valid["refresh"] {
+		zone.Refresh = uint32(body["refresh"].(float64))
+	}
+	if valid["retry"] {
+		zone.Retry = uint32(body["retry"].(float64))
+	}
+	if valid["expire"] {
+		zone.Expire = uint32(body["expire"].(float64))
+	}
+	if valid["ttl"] {
+		zone.TTL = uint32(body["ttl"].(float64))
+	}
+	if valid["negative-ttl"] {
+		zone.NegativeTTL = uint32(body["negative-ttl"].(float64))
+	}
+
+	if valid["allow-transfer"] {
+		zone.AllowTransfer, _ = util.ConvertArrayToString(body["allow-transfer"].([]interface{}))
+	}
+	if valid["transfer-keys"] {
+		zone.TransferKeys, _ = util.ConvertArrayToString(body["transfer-keys"].([]interface{}))
+	}
+
+	// Secondaries need a new serial to notice the change
+	zone.Serial++
+
+	// Save to database
+	if err := zone.Encode(database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write zone to database: "+err.Error())
+		return
+	}
+
+	// Record the new serial in the journal used for incremental transfers
+	if err := authority.Journal(zone.Name, database); err != nil {
+		log.Printf("Failed to journal zone '%s': %v", zone.Name, err)
+	}
+
+	util.Responses.Success(w)
+}